@@ -0,0 +1,266 @@
+package derp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is wrapped by every error that a Breaker returns instead of calling
+// its function, so that callers can recognize it with errors.Is.
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerState describes whether a Breaker is letting calls through.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through, and counts consecutive failures.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen rejects every call until its cooldown has passed.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single trial call through to decide whether to close again.
+	BreakerHalfOpen
+)
+
+// String implements the fmt.Stringer interface.
+func (state BreakerState) String() string {
+
+	switch state {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+
+	return "unknown"
+}
+
+// Breaker is a circuit breaker that stops calling a failing upstream.  It counts consecutive
+// failures (classified by IsFailure), opens after Threshold of them, and then rejects calls
+// with a (503) Service Unavailable error until its cooldown passes.  It then half-opens,
+// letting one trial call through: success closes the breaker, and failure opens it again.
+//
+// When the failure that opened the breaker carries a positive retry-after duration (such as
+// the Retry-After header of an HTTPError), that duration is used instead of Cooldown.
+//
+// A Breaker is safe for concurrent use, and must not be copied after first use.
+type Breaker struct {
+	Threshold int              // Consecutive failures that open the breaker
+	Cooldown  time.Duration    // Time the breaker stays open before it half-opens
+	IsFailure func(error) bool // Classifies errors as upstream failures.  If nil, see isBreakerFailure.
+
+	lock      sync.Mutex   // guards every field below
+	state     BreakerState // current state
+	failures  int          // consecutive failures counted while closed
+	openUntil time.Time    // when an open breaker half-opens
+	trial     uint64       // token of the half-open trial call in flight, or zero if there is none
+	trials    uint64       // number of trial calls ever allowed, which makes each token unique
+}
+
+// NewBreaker returns a closed Breaker that opens after `threshold` consecutive
+// failures, and stays open for `cooldown` before half-opening.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+	}
+}
+
+// Do calls fn if the breaker allows it, and records the result.  While the breaker is open,
+// fn is not called, and Do returns a (503) Service Unavailable error that wraps
+// ErrBreakerOpen and carries the time remaining until the breaker half-opens.
+func (breaker *Breaker) Do(fn func() error) error {
+
+	trial, err := breaker.allow()
+
+	if err != nil {
+		return err
+	}
+
+	// RULE: A panicking fn still counts as a failure, or a half-open
+	// breaker would wait forever for its trial call to finish.
+	completed := false
+
+	defer func() {
+		if !completed {
+			breaker.record(trial, Internal("derp.Breaker.Do", "Function panicked"))
+		}
+	}()
+
+	err = fn()
+	completed = true
+	breaker.record(trial, err)
+	return err
+}
+
+// State returns the current state of the breaker.
+func (breaker *Breaker) State() BreakerState {
+
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	// An expired cooldown means the next call will be a trial
+	if breaker.state == BreakerOpen && !time.Now().Before(breaker.openUntil) {
+		return BreakerHalfOpen
+	}
+
+	return breaker.state
+}
+
+// Reset closes the breaker and forgets all counted failures.
+func (breaker *Breaker) Reset() {
+
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	breaker.state = BreakerClosed
+	breaker.failures = 0
+	breaker.trial = 0
+}
+
+// allow returns nil if a call may proceed, or the error that rejects it.  A call
+// that is the half-open trial also receives a non-zero token, which it passes to record.
+func (breaker *Breaker) allow() (uint64, error) {
+
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	now := time.Now()
+
+	if breaker.state == BreakerOpen {
+
+		if now.Before(breaker.openUntil) {
+			return 0, breakerOpenError(breaker.openUntil.Sub(now))
+		}
+
+		breaker.state = BreakerHalfOpen
+	}
+
+	if breaker.state == BreakerHalfOpen {
+
+		// RULE: Only one trial call at a time, so that a recovering upstream is not flooded.
+		if breaker.trial != 0 {
+			return 0, breakerOpenError(0)
+		}
+
+		breaker.trials++
+		breaker.trial = breaker.trials
+		return breaker.trial, nil
+	}
+
+	return 0, nil
+}
+
+// record updates the breaker with the result of a call, and the token that allow gave it.
+func (breaker *Breaker) record(trial uint64, err error) {
+
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	failed := breaker.isFailure(err)
+
+	// RULE: Only the trial call decides whether a half-open breaker closes or opens again.
+	if trial != 0 {
+
+		// A trial from before a Reset no longer owns the breaker
+		if trial != breaker.trial {
+			return
+		}
+
+		breaker.trial = 0
+
+		if failed {
+			breaker.open(err)
+			return
+		}
+
+		breaker.state = BreakerClosed
+		breaker.failures = 0
+		return
+	}
+
+	// Calls allowed while closed finish late, so they only count if the breaker is still closed
+	if breaker.state != BreakerClosed {
+		return
+	}
+
+	if !failed {
+		breaker.failures = 0
+		return
+	}
+
+	breaker.failures++
+
+	if breaker.failures >= breaker.Threshold {
+		breaker.open(err)
+	}
+}
+
+// open opens the breaker, for a cooldown that depends on the failure that opened it.
+func (breaker *Breaker) open(err error) {
+	breaker.state = BreakerOpen
+	breaker.openUntil = time.Now().Add(breaker.cooldown(err))
+}
+
+// cooldown returns how long the breaker stays open after the given failure.
+func (breaker *Breaker) cooldown(err error) time.Duration {
+
+	// RULE: A zero hint (such as "Retry-After: 0") would half-open the breaker immediately
+	if retryAfter, ok := retryAfterHint(err); ok && retryAfter > 0 {
+		return retryAfter
+	}
+
+	return breaker.Cooldown
+}
+
+// isFailure returns TRUE if the error counts against the upstream.
+func (breaker *Breaker) isFailure(err error) bool {
+
+	if IsNil(err) {
+		return false
+	}
+
+	if breaker.IsFailure != nil {
+		return breaker.IsFailure(err)
+	}
+
+	return isBreakerFailure(err)
+}
+
+// isBreakerFailure is the default failure classification: server errors, rate limits,
+// and timeouts count against the upstream, while client errors (such as a 404) mean
+// that the upstream is healthy and answering correctly.  Canceled calls were abandoned
+// by the caller, so they say nothing about the upstream either.
+func isBreakerFailure(err error) bool {
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if IsServerError(err) {
+		return true
+	}
+
+	if tooMany, _ := IsTooManyRequests(err); tooMany {
+		return true
+	}
+
+	return isRetryableDefault(err)
+}
+
+// breakerOpenError returns the error for a call rejected by an open breaker.
+func breakerOpenError(retryAfter time.Duration) Error {
+	return newError(
+		codeServiceUnavailableError,
+		"derp.Breaker.Do",
+		"Circuit breaker is open",
+		WithWrappedValue(ErrBreakerOpen),
+		WithRetryAfter(retryAfter),
+		WithRetryable(true),
+	)
+}
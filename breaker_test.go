@@ -0,0 +1,206 @@
+package derp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+
+	breaker := NewBreaker(3, time.Hour)
+	failure := BadGateway("location", "upstream failed")
+
+	for index := 0; index < 3; index++ {
+		require.Equal(t, BreakerClosed, breaker.State())
+		require.Equal(t, failure, breaker.Do(func() error { return failure }))
+	}
+
+	require.Equal(t, BreakerOpen, breaker.State())
+
+	// Open breakers do not call the function
+	called := false
+	err := breaker.Do(func() error {
+		called = true
+		return nil
+	})
+
+	require.False(t, called)
+	require.True(t, errors.Is(err, ErrBreakerOpen))
+	require.Equal(t, codeServiceUnavailableError, ErrorCode(err))
+	require.True(t, IsServerError(err))
+	require.True(t, IsRetryable(err))
+	require.InDelta(t, time.Hour, RetryAfter(err), float64(time.Second))
+}
+
+func TestBreaker_SuccessResetsCount(t *testing.T) {
+
+	breaker := NewBreaker(2, time.Hour)
+	failure := Timeout("location", "slow")
+
+	require.Error(t, breaker.Do(func() error { return failure }))
+	require.NoError(t, breaker.Do(func() error { return nil }))
+	require.Error(t, breaker.Do(func() error { return failure }))
+
+	// Failures were not consecutive, so the breaker is still closed
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreaker_ClientErrorsAreNotFailures(t *testing.T) {
+
+	breaker := NewBreaker(1, time.Hour)
+
+	require.Error(t, breaker.Do(func() error { return NotFound("location", "missing") }))
+	require.Equal(t, BreakerClosed, breaker.State())
+
+	// Canceled calls were abandoned by the caller, and say nothing about the upstream
+	require.Error(t, breaker.Do(func() error { return context.Canceled }))
+	require.Error(t, breaker.Do(func() error { return Wrap(context.Canceled, "location", "abandoned") }))
+	require.Equal(t, BreakerClosed, breaker.State())
+
+	require.Error(t, breaker.Do(func() error { return newError(codeTooManyRequestsError, "location", "slow down") }))
+	require.Equal(t, BreakerOpen, breaker.State())
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+
+	breaker := NewBreaker(1, 10*time.Millisecond)
+	failure := Internal("location", "broken")
+
+	require.Error(t, breaker.Do(func() error { return failure }))
+	require.Equal(t, BreakerOpen, breaker.State())
+
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, BreakerHalfOpen, breaker.State())
+
+	// A failed trial opens the breaker again
+	require.Equal(t, failure, breaker.Do(func() error { return failure }))
+	require.Equal(t, BreakerOpen, breaker.State())
+
+	time.Sleep(20 * time.Millisecond)
+
+	// A successful trial closes it
+	require.NoError(t, breaker.Do(func() error { return nil }))
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreaker_SingleTrial(t *testing.T) {
+
+	breaker := NewBreaker(1, time.Millisecond)
+	require.Error(t, breaker.Do(func() error { return Internal("location", "broken") }))
+	time.Sleep(5 * time.Millisecond)
+
+	// While one trial is in flight, other calls are rejected
+	err := breaker.Do(func() error {
+		return breaker.Do(func() error { return nil })
+	})
+
+	require.True(t, errors.Is(err, ErrBreakerOpen))
+}
+
+func TestBreaker_LateResults(t *testing.T) {
+
+	breaker := NewBreaker(1, 10*time.Millisecond)
+
+	// slowCall starts a call that finishes when its channel is closed
+	slowCall := func() (chan struct{}, chan error) {
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan error, 1)
+
+		go func() {
+			done <- breaker.Do(func() error {
+				close(started)
+				<-release
+				return nil
+			})
+		}()
+
+		<-started
+		return release, done
+	}
+
+	// A call allowed while closed is still running when the breaker opens
+	late, lateDone := slowCall()
+	require.Error(t, breaker.Do(func() error { return Internal("location", "broken") }))
+	require.Equal(t, BreakerOpen, breaker.State())
+
+	// The breaker half-opens, and its trial call is still running
+	time.Sleep(20 * time.Millisecond)
+	trial, trialDone := slowCall()
+
+	// The late success neither closes the breaker, nor ends the trial
+	close(late)
+	require.NoError(t, <-lateDone)
+	require.Equal(t, BreakerHalfOpen, breaker.State())
+	require.True(t, errors.Is(breaker.Do(func() error { return nil }), ErrBreakerOpen))
+
+	// Only the trial closes it
+	close(trial)
+	require.NoError(t, <-trialDone)
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreaker_Panic(t *testing.T) {
+
+	breaker := NewBreaker(1, time.Hour)
+
+	require.Panics(t, func() {
+		_ = breaker.Do(func() error { panic("boom") })
+	})
+
+	require.Equal(t, BreakerOpen, breaker.State())
+}
+
+func TestBreaker_RetryAfter(t *testing.T) {
+
+	breaker := NewBreaker(1, time.Hour)
+
+	// The upstream's Retry-After header replaces the configured cooldown
+	failure := HTTPError{Response: HTTPResponseReport{
+		StatusCode: 503,
+		Header:     http.Header{"Retry-After": []string{"1"}},
+	}}
+
+	require.Error(t, breaker.Do(func() error { return failure }))
+	require.Equal(t, BreakerOpen, breaker.State())
+	require.WithinDuration(t, time.Now().Add(time.Second), breaker.openUntil, 100*time.Millisecond)
+
+	// ...but a zero (or missing) hint does not half-open the breaker immediately
+	for _, header := range []http.Header{{"Retry-After": []string{"0"}}, {}} {
+
+		breaker.Reset()
+		failure.Response.Header = header
+
+		require.Error(t, breaker.Do(func() error { return failure }))
+		require.Equal(t, BreakerOpen, breaker.State())
+		require.WithinDuration(t, time.Now().Add(time.Hour), breaker.openUntil, 100*time.Millisecond)
+	}
+}
+
+func TestBreaker_IsFailure(t *testing.T) {
+
+	breaker := NewBreaker(1, time.Hour)
+	breaker.IsFailure = IsNotFound
+
+	require.Error(t, breaker.Do(func() error { return Internal("location", "broken") }))
+	require.Equal(t, BreakerClosed, breaker.State())
+
+	require.Error(t, breaker.Do(func() error { return NotFound("location", "missing") }))
+	require.Equal(t, BreakerOpen, breaker.State())
+
+	breaker.Reset()
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerState_String(t *testing.T) {
+	require.Equal(t, "closed", BreakerClosed.String())
+	require.Equal(t, "open", BreakerOpen.String())
+	require.Equal(t, "half-open", BreakerHalfOpen.String())
+	require.Equal(t, "unknown", BreakerState(99).String())
+}
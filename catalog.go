@@ -0,0 +1,215 @@
+package derp
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog resolves message keys into localized, user-facing text.
+type Catalog interface {
+
+	// Lookup returns the message for a key in the requested language (such as "en" or
+	// "pt-BR").  An empty language requests the catalog's default language.
+	Lookup(language string, key string) (string, bool)
+}
+
+// CodeMessageKey returns the catalog key that PublicMessage uses for errors without a
+// message key of their own, such as "code.404".  Catalogs can translate these keys
+// to replace derp's built-in English fallbacks.
+func CodeMessageKey(code int) string {
+	return "code." + strconv.Itoa(code)
+}
+
+/******************************************
+ * In-Memory Catalog
+ ******************************************/
+
+// MemoryCatalog is a Catalog that keeps its messages in memory.
+// It is safe for concurrent use.
+type MemoryCatalog struct {
+	DefaultLanguage string // Language used when no requested language has a message
+
+	lock     sync.RWMutex
+	messages map[string]map[string]string // language -> key -> message
+}
+
+// NewMemoryCatalog returns an empty MemoryCatalog that falls back to the default language.
+func NewMemoryCatalog(defaultLanguage string) *MemoryCatalog {
+	return &MemoryCatalog{
+		DefaultLanguage: defaultLanguage,
+		messages:        make(map[string]map[string]string),
+	}
+}
+
+// Set adds (or replaces) the message for a key in one language.
+func (catalog *MemoryCatalog) Set(language string, key string, message string) {
+
+	catalog.lock.Lock()
+	defer catalog.lock.Unlock()
+
+	if catalog.messages == nil {
+		catalog.messages = make(map[string]map[string]string)
+	}
+
+	language = normalizeLanguage(language)
+
+	if catalog.messages[language] == nil {
+		catalog.messages[language] = make(map[string]string)
+	}
+
+	catalog.messages[language][key] = message
+}
+
+// Lookup implements the Catalog interface.  Regional languages (such as "en-GB")
+// fall back to their base language ("en") when they have no message of their own.
+func (catalog *MemoryCatalog) Lookup(language string, key string) (string, bool) {
+
+	catalog.lock.RLock()
+	defer catalog.lock.RUnlock()
+
+	if language == "" {
+		language = catalog.DefaultLanguage
+	}
+
+	language = normalizeLanguage(language)
+
+	if message, ok := catalog.messages[language][key]; ok {
+		return message, true
+	}
+
+	if base, _, found := strings.Cut(language, "-"); found {
+		if message, ok := catalog.messages[base][key]; ok {
+			return message, true
+		}
+	}
+
+	return "", false
+}
+
+// replace swaps every message in the catalog at once.
+func (catalog *MemoryCatalog) replace(messages map[string]map[string]string) {
+
+	normalized := make(map[string]map[string]string, len(messages))
+
+	for language, keys := range messages {
+		normalized[normalizeLanguage(language)] = keys
+	}
+
+	catalog.lock.Lock()
+	defer catalog.lock.Unlock()
+
+	catalog.messages = normalized
+}
+
+/******************************************
+ * JSON File Catalog
+ ******************************************/
+
+// FileCatalog is a Catalog that reads its messages from a JSON file, shaped as
+// an object of languages, each holding an object of keys and messages:
+//
+//	{"en": {"user.missing": "We couldn't find that user."}, "fr": {...}}
+type FileCatalog struct {
+	*MemoryCatalog
+	Filename string // JSON file that the messages are read from
+}
+
+// NewFileCatalog returns a FileCatalog populated from the provided JSON file.
+func NewFileCatalog(filename string, defaultLanguage string) (*FileCatalog, error) {
+
+	result := &FileCatalog{
+		MemoryCatalog: NewMemoryCatalog(defaultLanguage),
+		Filename:      filename,
+	}
+
+	if err := result.Reload(); err != nil {
+		return nil, Wrap(err, "derp.NewFileCatalog", "Unable to load catalog")
+	}
+
+	return result, nil
+}
+
+// Reload reads the JSON file again, and replaces every message in the catalog.
+// If the file cannot be read, the existing messages are kept.
+func (catalog *FileCatalog) Reload() error {
+
+	const location = "derp.FileCatalog.Reload"
+
+	content, err := os.ReadFile(catalog.Filename)
+
+	if err != nil {
+		return Internal(location, "Unable to read catalog file", catalog.Filename, WithWrappedValue(err))
+	}
+
+	var messages map[string]map[string]string
+
+	if err := json.Unmarshal(content, &messages); err != nil {
+		return Internal(location, "Unable to parse catalog file", catalog.Filename, WithWrappedValue(err))
+	}
+
+	catalog.replace(messages)
+	return nil
+}
+
+/******************************************
+ * Language Negotiation
+ ******************************************/
+
+// ParseAcceptLanguage returns the languages listed in an Accept-Language header,
+// ordered by preference.  Wildcards and languages with a zero quality are skipped.
+func ParseAcceptLanguage(header string) []string {
+
+	type weighted struct {
+		language string
+		quality  float64
+	}
+
+	entries := make([]weighted, 0)
+
+	for _, part := range strings.Split(header, ",") {
+
+		language, parameters, _ := strings.Cut(strings.TrimSpace(part), ";")
+		language = normalizeLanguage(language)
+
+		if language == "" || language == "*" {
+			continue
+		}
+
+		quality := 1.0
+
+		if value, found := strings.CutPrefix(strings.TrimSpace(parameters), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		entries = append(entries, weighted{language: language, quality: quality})
+	}
+
+	// Stable, so that equally-weighted languages keep the order the client sent
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+
+	result := make([]string, len(entries))
+
+	for index, entry := range entries {
+		result[index] = entry.language
+	}
+
+	return result
+}
+
+// normalizeLanguage converts a language tag into the form used for lookups,
+// so that "en_US" and "EN-us" both find the messages stored for "en-us".
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
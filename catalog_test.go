@@ -0,0 +1,85 @@
+package derp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCatalog_Lookup(t *testing.T) {
+
+	catalog := NewMemoryCatalog("en")
+	catalog.Set("en", "user.missing", "We couldn't find that user.")
+	catalog.Set("en-GB", "user.missing", "We couldn't find that user, I'm afraid.")
+	catalog.Set("fr", "user.missing", "Utilisateur introuvable.")
+
+	lookup := func(language string) string {
+		message, _ := catalog.Lookup(language, "user.missing")
+		return message
+	}
+
+	require.Equal(t, "We couldn't find that user, I'm afraid.", lookup("en-GB"))
+	require.Equal(t, "We couldn't find that user, I'm afraid.", lookup("en_gb"))
+	require.Equal(t, "Utilisateur introuvable.", lookup("fr-CA"))
+	require.Equal(t, "We couldn't find that user.", lookup(""))
+	require.Equal(t, "", lookup("de"))
+
+	_, ok := catalog.Lookup("en", "unknown.key")
+	require.False(t, ok)
+}
+
+func TestMemoryCatalog_ZeroValue(t *testing.T) {
+
+	var catalog MemoryCatalog
+	catalog.Set("en", "key", "message")
+
+	message, ok := catalog.Lookup("en", "key")
+	require.True(t, ok)
+	require.Equal(t, "message", message)
+}
+
+func TestFileCatalog(t *testing.T) {
+
+	filename := filepath.Join(t.TempDir(), "catalog.json")
+	require.Nil(t, os.WriteFile(filename, []byte(`{"en": {"user.missing": "Missing"}, "DE": {"user.missing": "Fehlt"}}`), 0o600))
+
+	catalog, err := NewFileCatalog(filename, "en")
+	require.Nil(t, err)
+
+	message, _ := catalog.Lookup("de-AT", "user.missing")
+	require.Equal(t, "Fehlt", message)
+
+	// Reloading replaces every message
+	require.Nil(t, os.WriteFile(filename, []byte(`{"en": {"user.missing": "Gone missing"}}`), 0o600))
+	require.Nil(t, catalog.Reload())
+
+	message, _ = catalog.Lookup("", "user.missing")
+	require.Equal(t, "Gone missing", message)
+
+	_, ok := catalog.Lookup("de", "user.missing")
+	require.False(t, ok)
+
+	// A broken file keeps the existing messages
+	require.Nil(t, os.WriteFile(filename, []byte(`not json`), 0o600))
+	require.NotNil(t, catalog.Reload())
+
+	message, _ = catalog.Lookup("en", "user.missing")
+	require.Equal(t, "Gone missing", message)
+}
+
+func TestFileCatalog_Missing(t *testing.T) {
+
+	catalog, err := NewFileCatalog(filepath.Join(t.TempDir(), "missing.json"), "en")
+	require.Nil(t, catalog)
+	require.NotNil(t, err)
+	require.Equal(t, codeInternalError, ErrorCode(err))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+
+	require.Equal(t, []string{}, ParseAcceptLanguage(""))
+	require.Equal(t, []string{"fr-ch", "fr", "en", "de"}, ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5"))
+	require.Equal(t, []string{"de", "en"}, ParseAcceptLanguage("en;q=0.5, de, es;q=0"))
+}
@@ -0,0 +1,32 @@
+package derp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// clock is the function that derp uses to read the current time.  A nil value means time.Now.
+var clock atomic.Pointer[func() time.Time]
+
+// SetClock replaces the function that derp uses to timestamp errors and generate
+// IDs, so that tests can produce deterministic output.  Passing nil restores time.Now.
+func SetClock(now func() time.Time) {
+
+	if now == nil {
+		clock.Store(nil)
+		return
+	}
+
+	clock.Store(&now)
+}
+
+// Now returns the current time, as reported by the clock configured with SetClock.
+// Packages that build derp errors by hand should use it to timestamp them.
+func Now() time.Time {
+
+	if now := clock.Load(); now != nil {
+		return (*now)()
+	}
+
+	return time.Now()
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/benpate/derp"
+	"gopkg.in/yaml.v3"
+)
+
+// catalog is the contents of a catalog file.
+type catalog struct {
+	Package string      `yaml:"package"` // Package of the generated code, unless overridden
+	Prefix  string      `yaml:"prefix"`  // Prefix of every message key.  If empty, the package name is used.
+	Imports []string    `yaml:"imports"` // Packages that parameter types refer to, such as "time"
+	Errors  []errorSpec `yaml:"errors"`
+}
+
+// errorSpec describes one named error.
+type errorSpec struct {
+	Name        string      `yaml:"name"`        // Exported Go name of the constructor, such as "CardDeclined"
+	Code        int         `yaml:"code"`        // Error code, such as 402
+	Message     string      `yaml:"message"`     // Message template, with a {placeholder} for each param
+	Params      []paramSpec `yaml:"params"`      // Parameters of the constructor, in order
+	URL         string      `yaml:"url"`         // Web page with more information about the error
+	Key         string      `yaml:"key"`         // Message key.  If empty, it is derived from the prefix and name.
+	Description string      `yaml:"description"` // Longer explanation, for doc comments and the markdown reference
+}
+
+// paramSpec describes one parameter of a message template.
+type paramSpec struct {
+	Name string `yaml:"name"` // Placeholder in the message template, such as "cardId"
+	Type string `yaml:"type"` // Go type of the constructor argument.  If empty, "any" is used.
+}
+
+// parseCatalog reads a catalog from YAML or JSON content.  (JSON is valid YAML.)
+// Unknown fields are rejected, so that typos are not silently ignored.
+func parseCatalog(content []byte) (catalog, error) {
+
+	var result catalog
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&result); err != nil {
+		return result, derp.Validation("Unable to parse catalog", derp.WithLocation("derpgen.parseCatalog"), derp.WithWrappedValue(err))
+	}
+
+	return result, nil
+}
+
+// validate checks every error in the catalog, and fills in default values.
+func (catalog *catalog) validate() error {
+
+	const location = "derpgen.catalog.validate"
+
+	if !token.IsIdentifier(catalog.Package) {
+		return derp.Validation("Package name {package} is not valid.  Use -package, or run derpgen with go:generate", derp.Param("package", catalog.Package), derp.WithLocation(location))
+	}
+
+	if catalog.Prefix == "" {
+		catalog.Prefix = catalog.Package
+	}
+
+	if len(catalog.Errors) == 0 {
+		return derp.Validation("Catalog has no errors", derp.WithLocation(location))
+	}
+
+	names := make(map[string]bool, len(catalog.Errors))
+	keys := make(map[string]bool, len(catalog.Errors))
+
+	for index := range catalog.Errors {
+
+		spec := &catalog.Errors[index]
+
+		if err := spec.validate(catalog.Prefix); err != nil {
+			return derp.Wrap(err, location, "Invalid error", derp.WithField("index", index))
+		}
+
+		if names[spec.Name] {
+			return derp.Validation("Error {name} is declared twice", derp.Param("name", spec.Name), derp.WithLocation(location))
+		}
+
+		if keys[spec.Key] {
+			return derp.Validation("Message key {key} is used twice", derp.Param("key", spec.Key), derp.WithLocation(location))
+		}
+
+		names[spec.Name] = true
+		keys[spec.Key] = true
+	}
+
+	// Generated identifiers (IsCardDeclined, MessageKeyCardDeclined) must not collide with other errors
+	for name := range names {
+		if names["Is"+name] || names["MessageKey"+name] {
+			return derp.Validation("Error {name} collides with a generated helper", derp.Param("name", name), derp.WithLocation(location))
+		}
+	}
+
+	return nil
+}
+
+// validate checks one error, and fills in its default message key and parameter types.
+func (spec *errorSpec) validate(prefix string) error {
+
+	const location = "derpgen.errorSpec.validate"
+
+	if !token.IsIdentifier(spec.Name) || !token.IsExported(spec.Name) {
+		return derp.Validation("Name {name} must be an exported Go identifier", derp.Param("name", spec.Name), derp.WithLocation(location))
+	}
+
+	if spec.Code == 0 {
+		return derp.Validation("Error {name} has no code", derp.Param("name", spec.Name), derp.WithLocation(location))
+	}
+
+	if strings.TrimSpace(spec.Message) == "" {
+		return derp.Validation("Error {name} has no message", derp.Param("name", spec.Name), derp.WithLocation(location))
+	}
+
+	if spec.Key == "" {
+		spec.Key = prefix + "." + snakeCase(spec.Name)
+	}
+
+	// Every placeholder needs a parameter, and every parameter needs a placeholder
+	placeholders := templatePlaceholders(spec.Message)
+	identifiers := make(map[string]bool, len(spec.Params))
+
+	for index := range spec.Params {
+
+		param := &spec.Params[index]
+
+		if !placeholders[param.Name] {
+			return derp.Validation("Param {param} of error {name} is not in its message", derp.Param("param", param.Name), derp.Param("name", spec.Name), derp.WithLocation(location))
+		}
+
+		delete(placeholders, param.Name)
+
+		if param.Type == "" {
+			param.Type = "any"
+		}
+
+		if _, err := parser.ParseExpr(param.Type); err != nil {
+			return derp.Validation("Param {param} of error {name} has an invalid type", derp.Param("param", param.Name), derp.Param("name", spec.Name), derp.WithLocation(location), derp.WithWrappedValue(err))
+		}
+
+		identifier := param.Identifier()
+
+		if identifiers[identifier] {
+			return derp.Validation("Params of error {name} collide as {identifier}", derp.Param("name", spec.Name), derp.Param("identifier", identifier), derp.WithLocation(location))
+		}
+
+		identifiers[identifier] = true
+	}
+
+	if len(placeholders) > 0 {
+		return derp.Validation("Placeholders {placeholders} of error {name} have no params", derp.Param("placeholders", sortedKeys(placeholders)), derp.Param("name", spec.Name), derp.WithLocation(location))
+	}
+
+	return nil
+}
+
+// reservedIdentifiers are the names that generated constructors already use.
+var reservedIdentifiers = map[string]bool{
+	"derp":     true,
+	"details":  true,
+	"location": true,
+	"options":  true,
+}
+
+// Identifier returns the Go name of the parameter's constructor argument.
+func (param paramSpec) Identifier() string {
+
+	result := lowerCamelCase(param.Name)
+
+	// Avoid keywords, and names that would shadow the other arguments, the
+	// generated local variable, or the derp package
+	switch {
+	case result == "":
+		return "param"
+
+	case token.IsKeyword(result), reservedIdentifiers[result]:
+		return result + "Value"
+	}
+
+	return result
+}
+
+/******************************************
+ * Text Helpers
+ ******************************************/
+
+// templatePlaceholders returns the name of every {placeholder} in a message template,
+// matching the way that derp renders templates.
+func templatePlaceholders(template string) map[string]bool {
+
+	result := make(map[string]bool)
+
+	for {
+		start := strings.IndexByte(template, '{')
+
+		if start < 0 {
+			return result
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+
+		if end < 0 {
+			return result
+		}
+
+		end += start
+		result[template[start+1:end]] = true
+		template = template[end+1:]
+	}
+}
+
+// lowerCamelCase converts a name such as "card_id" into a Go identifier such as "cardId".
+func lowerCamelCase(name string) string {
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var result strings.Builder
+
+	for index, word := range words {
+
+		runes := []rune(word)
+
+		if index == 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		} else {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+
+		result.WriteString(string(runes))
+	}
+
+	// Identifiers cannot start with a digit
+	if value := result.String(); value != "" && unicode.IsDigit([]rune(value)[0]) {
+		return "p" + value
+	}
+
+	return result.String()
+}
+
+// snakeCase converts a Go name such as "CardDeclined" or "HTTPFailure" into "card_declined" or "http_failure".
+func snakeCase(name string) string {
+
+	runes := []rune(name)
+
+	var result strings.Builder
+
+	for index, r := range runes {
+
+		// Start a new word at each upper-case letter that follows a lower-case letter,
+		// or that begins a new word after an acronym ("HTTPFailure" -> "http_failure")
+		if index > 0 && unicode.IsUpper(r) {
+
+			previous := runes[index-1]
+			nextIsLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				result.WriteByte('_')
+			}
+		}
+
+		result.WriteRune(unicode.ToLower(r))
+	}
+
+	return result.String()
+}
+
+// sortedKeys returns the keys of a set, in order.
+func sortedKeys(set map[string]bool) string {
+
+	result := make([]string, 0, len(set))
+
+	for key := range set {
+		result = append(result, key)
+	}
+
+	sort.Strings(result)
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestExample verifies that the committed example matches what derpgen generates
+// today.  If this fails, run `go generate ./cmd/derpgen/example`.
+func TestExample(t *testing.T) {
+
+	content, err := os.ReadFile(filepath.Join("example", "errors.yaml"))
+	require.Nil(t, err)
+
+	catalog, err := parseCatalog(content)
+	require.Nil(t, err)
+	require.Nil(t, catalog.validate())
+
+	code, err := generateGo(catalog, "errors.yaml")
+	require.Nil(t, err)
+
+	expectedCode, err := os.ReadFile(filepath.Join("example", "errors_gen.go"))
+	require.Nil(t, err)
+	require.Equal(t, string(expectedCode), string(code))
+	typeCheck(t, code)
+
+	expectedDoc, err := os.ReadFile(filepath.Join("example", "ERRORS.md"))
+	require.Nil(t, err)
+	require.Equal(t, string(expectedDoc), string(generateMarkdown(catalog, "errors.yaml")))
+}
+
+// typeCheck verifies that generated code compiles, which format.Source (a parser) cannot.
+func typeCheck(t *testing.T, code []byte) {
+
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "errors_gen.go", code, 0)
+	require.Nil(t, err)
+
+	config := types.Config{Importer: importer.ForCompiler(fileSet, "source", nil)}
+	_, err = config.Check("things", fileSet, []*ast.File{file}, nil)
+	require.Nil(t, err, string(code))
+}
+
+// TestGenerate_TypeCheck verifies that params named like the generated arguments,
+// locals, keywords, and imports still produce code that compiles.
+func TestGenerate_TypeCheck(t *testing.T) {
+
+	catalog, err := parseCatalog([]byte(`
+imports: [time]
+errors:
+  - name: Reserved
+    code: 400
+    message: "{options} {location} {details} {derp} {type} {err}"
+    params:
+      - {name: options, type: string}
+      - {name: location, type: int}
+      - {name: details, type: "[]string"}
+      - {name: derp}
+      - {name: type}
+      - {name: err, type: error}
+  - name: Timed
+    code: 524
+    message: "Waited {time}"
+    params:
+      - {name: time, type: time.Duration}
+  - name: Plain
+    code: 10042
+    message: "No params"
+    url: https://example.com/errors/plain
+`))
+	require.Nil(t, err)
+
+	catalog.Package = "things"
+	require.Nil(t, catalog.validate())
+
+	code, err := generateGo(catalog, "errors.yaml")
+	require.Nil(t, err)
+
+	typeCheck(t, code)
+}
+
+func TestRun(t *testing.T) {
+
+	directory := t.TempDir()
+	input := filepath.Join(directory, "errors.json")
+	doc := filepath.Join(directory, "ERRORS.md")
+
+	// JSON catalogs work too
+	require.Nil(t, os.WriteFile(input, []byte(`{"errors": [{"name": "Missing", "code": 404, "message": "{thing} is missing", "params": [{"name": "thing"}]}]}`), 0o600))
+	require.Nil(t, run([]string{"-in", input, "-doc", doc}, "things"))
+
+	code, err := os.ReadFile(filepath.Join(directory, "errors_gen.go"))
+	require.Nil(t, err)
+	require.Contains(t, string(code), "package things\n")
+	require.Contains(t, string(code), `MessageKeyMissing = "things.missing"`)
+	require.Contains(t, string(code), "func Missing(location string, thing any, details ...any) derp.Error {")
+
+	markdown, err := os.ReadFile(doc)
+	require.Nil(t, err)
+	require.Contains(t, string(markdown), "## Missing")
+
+	// The -package flag wins over $GOPACKAGE
+	output := filepath.Join(directory, "other.go")
+	require.Nil(t, run([]string{"-in", input, "-out", output, "-package", "other"}, "things"))
+
+	code, err = os.ReadFile(output)
+	require.Nil(t, err)
+	require.Contains(t, string(code), "package other\n")
+}
+
+func TestRun_Errors(t *testing.T) {
+
+	directory := t.TempDir()
+	input := filepath.Join(directory, "errors.yaml")
+	require.Nil(t, os.WriteFile(input, []byte("errors:\n  - {name: Missing, code: 404, message: missing}\n"), 0o600))
+
+	require.Error(t, run([]string{}, "things"))
+	require.Error(t, run([]string{"-unknown"}, "things"))
+	require.Error(t, run([]string{"-in", filepath.Join(directory, "missing.yaml")}, "things"))
+
+	// Without go:generate, the package must come from somewhere
+	err := run([]string{"-in", input}, "")
+	require.Error(t, err)
+	require.Contains(t, describe(err), "Package name  is not valid")
+}
+
+func TestValidate(t *testing.T) {
+
+	for name, content := range map[string]string{
+		"unknown field":    "errors:\n  - {name: Missing, code: 404, message: missing, unknown: true}",
+		"no errors":        "errors: []",
+		"unexported name":  "errors:\n  - {name: missing, code: 404, message: missing}",
+		"no code":          "errors:\n  - {name: Missing, message: missing}",
+		"no message":       "errors:\n  - {name: Missing, code: 404}",
+		"duplicate name":   "errors:\n  - {name: Missing, code: 404, message: missing}\n  - {name: Missing, code: 410, message: gone}",
+		"duplicate key":    "errors:\n  - {name: Missing, code: 404, message: missing}\n  - {name: Gone, code: 410, message: gone, key: things.missing}",
+		"helper collision": "errors:\n  - {name: Missing, code: 404, message: missing}\n  - {name: IsMissing, code: 404, message: missing}",
+		"unused param":     "errors:\n  - {name: Missing, code: 404, message: missing, params: [{name: id}]}",
+		"missing param":    "errors:\n  - {name: Missing, code: 404, message: '{id} missing'}",
+		"invalid type":     "errors:\n  - {name: Missing, code: 404, message: '{id} missing', params: [{name: id, type: 'map['}]}",
+		"colliding params": "errors:\n  - {name: Missing, code: 404, message: '{card_id} {cardId}', params: [{name: card_id}, {name: cardId}]}",
+		"duplicate param":  "errors:\n  - {name: Missing, code: 404, message: '{id} missing', params: [{name: id}, {name: id}]}",
+	} {
+		catalog, err := parseCatalog([]byte(content))
+
+		if err == nil {
+			catalog.Package = "things"
+			err = catalog.validate()
+		}
+
+		require.Error(t, err, name)
+	}
+}
+
+func TestParamIdentifier(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"cardId":   "cardId",
+		"card_id":  "cardId",
+		"Card-ID":  "cardID",
+		"type":     "typeValue",
+		"location": "locationValue",
+		"options":  "optionsValue",
+		"derp":     "derpValue",
+		"2fa":      "p2fa",
+		"---":      "param",
+	} {
+		require.Equal(t, expected, paramSpec{Name: name}.Identifier(), name)
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+
+	for name, expected := range map[string]string{
+		"CardDeclined": "card_declined",
+		"HTTPFailure":  "http_failure",
+		"Error404":     "error404",
+		"X":            "x",
+	} {
+		require.Equal(t, expected, snakeCase(name), name)
+	}
+}
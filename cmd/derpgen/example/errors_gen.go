@@ -0,0 +1,72 @@
+// Code generated by derpgen from errors.yaml. DO NOT EDIT.
+
+package example
+
+import (
+	"time"
+
+	"github.com/benpate/derp"
+)
+
+// Message keys that identify each error in this catalog.  Localize them
+// in a derp.Catalog to translate the public messages of these errors.
+const (
+	MessageKeyCardDeclined    = "billing.card_declined"
+	MessageKeyInvoiceNotFound = "billing.invoice_not_found"
+	MessageKeyLedgerLocked    = "billing.ledger_locked"
+)
+
+// CardDeclined returns a (402) error: Card {cardId} was declined
+//
+// The payment processor declined the card. Customers should use a different card.
+// https://example.com/errors/card-declined
+func CardDeclined(location string, cardId string, details ...any) derp.Error {
+
+	options := []any{
+		derp.Param("cardId", cardId),
+		derp.WithMessageKey(MessageKeyCardDeclined),
+		derp.WithURL("https://example.com/errors/card-declined"),
+	}
+
+	return derp.New(402, location, "Card {cardId} was declined", append(options, details...)...)
+}
+
+// IsCardDeclined returns TRUE if the error, or any error that it wraps, was created by CardDeclined.
+func IsCardDeclined(err error) bool {
+	return derp.HasMessageKey(err, MessageKeyCardDeclined)
+}
+
+// InvoiceNotFound returns a (404) error: Invoice {invoiceId} not found
+func InvoiceNotFound(location string, invoiceId int64, details ...any) derp.Error {
+
+	options := []any{
+		derp.Param("invoiceId", invoiceId),
+		derp.WithMessageKey(MessageKeyInvoiceNotFound),
+	}
+
+	return derp.New(404, location, "Invoice {invoiceId} not found", append(options, details...)...)
+}
+
+// IsInvoiceNotFound returns TRUE if the error, or any error that it wraps, was created by InvoiceNotFound.
+func IsInvoiceNotFound(err error) bool {
+	return derp.HasMessageKey(err, MessageKeyInvoiceNotFound)
+}
+
+// LedgerLocked returns a (503) error: Ledger is locked for {duration} by {owner}
+//
+// Another process is closing the books. The operation is worth retrying.
+func LedgerLocked(location string, duration time.Duration, owner any, details ...any) derp.Error {
+
+	options := []any{
+		derp.Param("duration", duration),
+		derp.Param("owner", owner),
+		derp.WithMessageKey(MessageKeyLedgerLocked),
+	}
+
+	return derp.New(503, location, "Ledger is locked for {duration} by {owner}", append(options, details...)...)
+}
+
+// IsLedgerLocked returns TRUE if the error, or any error that it wraps, was created by LedgerLocked.
+func IsLedgerLocked(err error) bool {
+	return derp.HasMessageKey(err, MessageKeyLedgerLocked)
+}
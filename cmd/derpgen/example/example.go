@@ -0,0 +1,5 @@
+// Package example shows the code that derpgen generates from a catalog of
+// named errors.  See errors.yaml, and the generated errors_gen.go and ERRORS.md.
+package example
+
+//go:generate go run github.com/benpate/derp/cmd/derpgen -in errors.yaml -doc ERRORS.md
@@ -0,0 +1,42 @@
+package example
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedErrors(t *testing.T) {
+
+	err := CardDeclined("billing.Charge", "card_123", "detail")
+
+	require.Equal(t, 402, derp.ErrorCode(err))
+	require.Equal(t, "billing.Charge", err.Location)
+	require.Equal(t, "Card card_123 was declined", err.Message)
+	require.Equal(t, "Card {cardId} was declined", derp.Template(err))
+	require.Equal(t, "https://example.com/errors/card-declined", derp.URL(err))
+	require.Equal(t, []any{"detail"}, err.Details)
+
+	// Is-helpers find generated errors anywhere in a chain
+	wrapped := fmt.Errorf("checkout: %w", derp.Wrap(err, "checkout.Submit", "Unable to submit order"))
+	require.True(t, IsCardDeclined(wrapped))
+	require.False(t, IsInvoiceNotFound(wrapped))
+	require.False(t, IsCardDeclined(derp.NotFound("location", "Card card_123 was declined")))
+
+	// Params keep their types, and options can be added as details
+	locked := LedgerLocked("ledger.Post", time.Minute, "closer", derp.WithRetryAfter(time.Minute))
+	require.Equal(t, "Ledger is locked for 1m0s by closer", locked.Message)
+	require.Equal(t, time.Minute, derp.RetryAfter(locked))
+	require.True(t, IsLedgerLocked(locked))
+}
+
+func TestGeneratedErrors_PublicMessage(t *testing.T) {
+
+	catalog := derp.NewMemoryCatalog("en")
+	catalog.Set("en", MessageKeyInvoiceNotFound, "We couldn't find that invoice.")
+
+	require.Equal(t, "We couldn't find that invoice.", derp.PublicMessage(InvoiceNotFound("invoices.Get", 42), catalog, "en"))
+}
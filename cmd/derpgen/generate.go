@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"github.com/benpate/derp"
+)
+
+// goTemplate renders the generated Go file.  Its output is passed through go/format,
+// so it only needs to be syntactically correct.
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{
+	"comment": comment,
+}).Parse(`// Code generated by derpgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Catalog.Package}}
+
+import (
+{{- range .Catalog.Imports}}
+	{{printf "%q" .}}
+{{- end}}
+
+	"github.com/benpate/derp"
+)
+
+// Message keys that identify each error in this catalog.  Localize them
+// in a derp.Catalog to translate the public messages of these errors.
+const (
+{{- range .Catalog.Errors}}
+	MessageKey{{.Name}} = {{printf "%q" .Key}}
+{{- end}}
+)
+{{range .Catalog.Errors}}
+// {{.Name}} returns a ({{.Code}}) error: {{comment .Message}}
+{{- if .Description}}
+//
+// {{comment .Description}}
+{{- end}}
+{{- if .URL}}
+// {{.URL}}
+{{- end}}
+func {{.Name}}(location string{{range .Params}}, {{.Identifier}} {{.Type}}{{end}}, details ...any) derp.Error {
+
+	options := []any{
+{{- range .Params}}
+		derp.Param({{printf "%q" .Name}}, {{.Identifier}}),
+{{- end}}
+		derp.WithMessageKey(MessageKey{{.Name}}),
+{{- if .URL}}
+		derp.WithURL({{printf "%q" .URL}}),
+{{- end}}
+	}
+
+	return derp.New({{.Code}}, location, {{printf "%q" .Message}}, append(options, details...)...)
+}
+
+// Is{{.Name}} returns TRUE if the error, or any error that it wraps, was created by {{.Name}}.
+func Is{{.Name}}(err error) bool {
+	return derp.HasMessageKey(err, MessageKey{{.Name}})
+}
+{{end}}`))
+
+// generateGo returns the formatted Go source of a validated catalog.
+func generateGo(catalog catalog, source string) ([]byte, error) {
+
+	const location = "derpgen.generateGo"
+
+	var buffer bytes.Buffer
+
+	if err := goTemplate.Execute(&buffer, map[string]any{"Catalog": catalog, "Source": source}); err != nil {
+		return nil, derp.Internal(location, "Unable to render code", derp.WithWrappedValue(err))
+	}
+
+	result, err := format.Source(buffer.Bytes())
+
+	if err != nil {
+		return nil, derp.Internal(location, "Generated code is not valid Go", buffer.String(), derp.WithWrappedValue(err))
+	}
+
+	return result, nil
+}
+
+// markdownTemplate renders the markdown reference.
+var markdownTemplate = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"cell":   cell,
+	"anchor": strings.ToLower,
+}).Parse(`# {{.Catalog.Package}} errors
+
+<!-- Code generated by derpgen from {{.Source}}. DO NOT EDIT. -->
+
+| Error | Code | Message |
+| --- | --- | --- |
+{{- range .Catalog.Errors}}
+| [{{.Name}}](#{{anchor .Name}}) | {{.Code}} | {{cell .Message}} |
+{{- end}}
+{{range .Catalog.Errors}}
+## {{.Name}}
+{{if .Description}}
+{{.Description}}
+{{end}}
+* **Code:** {{.Code}}
+* **Message:** ` + "`{{.Message}}`" + `
+* **Message key:** ` + "`{{.Key}}`" + `
+{{- if .Params}}
+* **Parameters:**{{range $index, $param := .Params}}{{if $index}},{{end}} ` + "`{{$param.Name}}`" + ` ({{$param.Type}}){{end}}
+{{- end}}
+{{- if .URL}}
+* **More information:** <{{.URL}}>
+{{- end}}
+{{end}}`))
+
+// generateMarkdown returns a markdown reference of every error in a validated catalog.
+func generateMarkdown(catalog catalog, source string) []byte {
+
+	var buffer bytes.Buffer
+
+	// The template only reads validated strings, so it cannot fail.
+	_ = markdownTemplate.Execute(&buffer, map[string]any{"Catalog": catalog, "Source": source})
+
+	return buffer.Bytes()
+}
+
+// comment collapses text onto a single line, so that it fits in a Go comment.
+func comment(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// cell escapes text for a markdown table cell.
+func cell(value string) string {
+	return strings.ReplaceAll(comment(value), "|", `\|`)
+}
@@ -0,0 +1,16 @@
+module github.com/benpate/derp/cmd/derpgen
+
+go 1.21
+
+require (
+	github.com/benpate/derp v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
+
+replace github.com/benpate/derp => ../../
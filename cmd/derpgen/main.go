@@ -0,0 +1,126 @@
+// Derpgen generates typed constructors for a catalog of named errors, so that each error
+// is declared once -- with its code, message template, parameters, and help URL -- instead
+// of being copy-pasted wherever it is returned.  Run it with go:generate:
+//
+//	//go:generate go run github.com/benpate/derp/cmd/derpgen -in errors.yaml -doc ERRORS.md
+//
+// The catalog is a YAML (or JSON) file:
+//
+//	errors:
+//	  - name: CardDeclined
+//	    code: 402
+//	    message: "Card {cardId} was declined"
+//	    params:
+//	      - {name: cardId, type: string}
+//	    url: https://example.com/errors/card-declined
+//	    description: The payment processor declined the card.
+//
+// For each error, derpgen writes a constructor (CardDeclined) that returns a derp.Error, an
+// Is-helper (IsCardDeclined) that finds the error anywhere in a chain of wrapped errors, and
+// a MessageKey constant that localizes the error's public message (see derp.Catalog).  With
+// -doc, it also writes a markdown reference of every error in the catalog.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+func main() {
+
+	if err := run(os.Args[1:], os.Getenv("GOPACKAGE")); err != nil {
+		fmt.Fprintln(os.Stderr, "derpgen: "+describe(err))
+		os.Exit(1)
+	}
+}
+
+// run generates the files requested by the command-line arguments.  The package name
+// defaults to $GOPACKAGE, which go:generate sets to the package of the calling file.
+func run(args []string, goPackage string) error {
+
+	const location = "derpgen.run"
+
+	flags := flag.NewFlagSet("derpgen", flag.ContinueOnError)
+	input := flags.String("in", "", "catalog file to read (YAML or JSON)")
+	output := flags.String("out", "", "Go file to write (default: the catalog name with a _gen.go suffix)")
+	doc := flags.String("doc", "", "markdown reference to write (optional)")
+	packageName := flags.String("package", "", "package of the generated code (default: $GOPACKAGE, then the catalog's package)")
+
+	if err := flags.Parse(args); err != nil {
+		return derp.BadRequest(location, "Invalid arguments", derp.WithWrappedValue(err))
+	}
+
+	if *input == "" {
+		return derp.BadRequest(location, "The -in flag is required")
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(*input, filepath.Ext(*input)) + "_gen.go"
+	}
+
+	content, err := os.ReadFile(*input)
+
+	if err != nil {
+		return derp.Internal(location, "Unable to read catalog", *input, derp.WithWrappedValue(err))
+	}
+
+	catalog, err := parseCatalog(content)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Invalid catalog", *input)
+	}
+
+	// Flags win over go:generate, which wins over the catalog itself
+	switch {
+	case *packageName != "":
+		catalog.Package = *packageName
+	case goPackage != "":
+		catalog.Package = goPackage
+	}
+
+	if err := catalog.validate(); err != nil {
+		return derp.Wrap(err, location, "Invalid catalog", *input)
+	}
+
+	source := filepath.Base(*input)
+
+	code, err := generateGo(catalog, source)
+
+	if err != nil {
+		return derp.Wrap(err, location, "Unable to generate code", *input)
+	}
+
+	if err := os.WriteFile(*output, code, 0o644); err != nil { //nolint:gosec // generated source files are meant to be readable
+		return derp.Internal(location, "Unable to write code", *output, derp.WithWrappedValue(err))
+	}
+
+	if *doc == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(*doc, generateMarkdown(catalog, source), 0o644); err != nil { //nolint:gosec // documentation is meant to be readable
+		return derp.Internal(location, "Unable to write documentation", *doc, derp.WithWrappedValue(err))
+	}
+
+	return nil
+}
+
+// describe returns the messages of every error in the chain, so that the
+// command line shows the root cause (such as a missing file) as well.
+func describe(err error) string {
+
+	messages := make([]string, 0, 2)
+
+	for derp.NotNil(err) {
+		messages = append(messages, derp.Message(err))
+		err = errors.Unwrap(err)
+	}
+
+	return strings.Join(messages, ": ")
+}
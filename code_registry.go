@@ -0,0 +1,246 @@
+package derp
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CodeInfo describes an error code: the HTTP status that clients receive, and the values
+// that PublicMessage, NewProblem, and IsRetryable use when an error does not provide its own.
+// Applications can register their own codes (such as 10042 "billing.card_declined") with
+// RegisterCode, in addition to the HTTP codes that derp registers itself.
+type CodeInfo struct {
+	Code       int    `json:"code"`                 // Numeric error code, such as 404 or 10042
+	Name       string `json:"name"`                 // Symbolic name, such as "not_found" or "billing.card_declined"
+	HTTPStatus int    `json:"httpStatus,omitempty"` // HTTP status sent to clients.  If zero, the code itself is used when it is a valid HTTP status.
+	Message    string `json:"message,omitempty"`    // Default user-facing message (see PublicMessage)
+	URL        string `json:"url,omitempty"`        // Web page with more information about this code (see NewProblem)
+	Retryable  *bool  `json:"retryable,omitempty"`  // Whether operations that fail with this code are worth retrying (see IsRetryable).  If nil, the code's previous registration (or else the status it maps onto) decides.
+}
+
+// codeRegistry contains every registered CodeInfo, by code and by name.
+type codeRegistry struct {
+	lock  sync.RWMutex
+	codes map[int]CodeInfo
+	names map[string]int
+}
+
+// codes is the global code registry, which starts with every code that derp's own constructors use.
+var codes = newCodeRegistry(
+	CodeInfo{Code: codeBadRequestError, Name: "bad_request", Message: "Bad request"},
+	CodeInfo{Code: codeUnauthorizedError, Name: "unauthorized", Message: "Please sign in to continue"},
+	CodeInfo{Code: codeForbiddenError, Name: "forbidden", Message: "You do not have permission to do that"},
+	CodeInfo{Code: codeNotFoundError, Name: "not_found", Message: "Not found"},
+	CodeInfo{Code: codeConflictError, Name: "conflict", Message: "This conflicts with a recent change"},
+	CodeInfo{Code: codeGoneError, Name: "gone", Message: "No longer available"},
+	CodeInfo{Code: codeTeapotError, Name: "teapot", Message: "I'm a teapot"},
+	CodeInfo{Code: codeMisdirectedRequestError, Name: "misdirected_request", Message: "Misdirected request"},
+	CodeInfo{Code: codeValidationError, Name: "validation", Message: "Some of the information provided is not valid"},
+	CodeInfo{Code: codeTooManyRequestsError, Name: "too_many_requests", Message: "Too many requests.  Please try again later", Retryable: boolPointer(true)},
+	CodeInfo{Code: CodeClientClosedRequest, Name: "client_closed_request", Message: "The request was canceled"},
+	CodeInfo{Code: codeInternalError, Name: "internal", Message: "Something went wrong"},
+	CodeInfo{Code: codeNotImplementedError, Name: "not_implemented", Message: "Not implemented"},
+	CodeInfo{Code: codeBadGatewayError, Name: "bad_gateway", Message: "A service we depend on is unavailable", Retryable: boolPointer(true)},
+	CodeInfo{Code: codeServiceUnavailableError, Name: "service_unavailable", Message: "Temporarily unavailable.  Please try again later", Retryable: boolPointer(true)},
+	CodeInfo{Code: codeGatewayTimeoutError, Name: "gateway_timeout", Message: "A service we depend on took too long to respond", Retryable: boolPointer(true)},
+	CodeInfo{Code: codeTimeout, Name: "timeout", Message: "This took too long.  Please try again later", Retryable: boolPointer(true)},
+)
+
+// newCodeRegistry returns a registry that contains the provided codes.
+func newCodeRegistry(infos ...CodeInfo) *codeRegistry {
+
+	result := &codeRegistry{
+		codes: make(map[int]CodeInfo, len(infos)),
+		names: make(map[string]int, len(infos)),
+	}
+
+	for _, info := range infos {
+		result.codes[info.Code] = info
+		result.names[info.Name] = info.Code
+	}
+
+	return result
+}
+
+/******************************************
+ * Registration
+ ******************************************/
+
+// RegisterCode adds an error code to the registry.  Registering an existing code replaces it,
+// which lets applications change the messages and URLs of derp's own codes.  A nil Retryable
+// keeps the previous decision, so that (503) stays retryable when only its message changes.
+// Every code needs a unique, non-empty name, and an HTTPStatus (if set) must be a valid HTTP status.
+func RegisterCode(info CodeInfo) error {
+
+	const location = "derp.RegisterCode"
+
+	if info.Code == 0 {
+		return Validation("Code is required", info.Name, WithLocation(location))
+	}
+
+	if strings.TrimSpace(info.Name) == "" {
+		return Validation("Name is required", info.Code, WithLocation(location))
+	}
+
+	if info.HTTPStatus != 0 && (info.HTTPStatus < 100 || info.HTTPStatus > 599) {
+		return Validation("HTTPStatus must be between 100 and 599", info.Code, info.HTTPStatus, WithLocation(location))
+	}
+
+	codes.lock.Lock()
+	defer codes.lock.Unlock()
+
+	if existing, ok := codes.names[info.Name]; ok && existing != info.Code {
+		return Conflict(location, "Name is already registered to another code", info.Name, existing)
+	}
+
+	// Release the previous name of a code that is being renamed, and keep its retry decision unless it is overridden
+	if previous, ok := codes.codes[info.Code]; ok {
+
+		delete(codes.names, previous.Name)
+
+		if info.Retryable == nil {
+			info.Retryable = previous.Retryable
+		}
+	}
+
+	codes.codes[info.Code] = info.clone()
+	codes.names[info.Name] = info.Code
+
+	return nil
+}
+
+// UnregisterCode removes an error code from the registry, and returns TRUE if it was
+// registered.  It is mainly useful in tests, which should leave the registry as they found it.
+func UnregisterCode(code int) bool {
+
+	codes.lock.Lock()
+	defer codes.lock.Unlock()
+
+	info, ok := codes.codes[code]
+
+	if !ok {
+		return false
+	}
+
+	delete(codes.names, info.Name)
+	delete(codes.codes, code)
+
+	return true
+}
+
+/******************************************
+ * Lookups
+ ******************************************/
+
+// LookupCode returns the registered CodeInfo for a numeric error code.
+func LookupCode(code int) (CodeInfo, bool) {
+
+	codes.lock.RLock()
+	defer codes.lock.RUnlock()
+
+	info, ok := codes.codes[code]
+	return info.clone(), ok
+}
+
+// LookupCodeName returns the registered CodeInfo for a symbolic name, such as "not_found".
+func LookupCodeName(name string) (CodeInfo, bool) {
+
+	codes.lock.RLock()
+	defer codes.lock.RUnlock()
+
+	if code, ok := codes.names[name]; ok {
+		return codes.codes[code].clone(), true
+	}
+
+	return CodeInfo{}, false
+}
+
+// RegisteredCodes returns every registered CodeInfo, sorted by code.
+func RegisteredCodes() []CodeInfo {
+
+	codes.lock.RLock()
+	defer codes.lock.RUnlock()
+
+	result := make([]CodeInfo, 0, len(codes.codes))
+
+	for _, info := range codes.codes {
+		result = append(result, info.clone())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+
+	return result
+}
+
+// CodeName returns the symbolic name of any error's code (such as "not_found"),
+// or an empty string if the code is not registered.
+func CodeName(err error) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	info, _ := LookupCode(ErrorCode(err))
+	return info.Name
+}
+
+// HTTPStatus returns the HTTP status code that describes any error to clients.  Registered
+// codes use their HTTPStatus, and codes outside the HTTP range are reported as (500)
+// Internal Server Errors.
+func HTTPStatus(err error) int {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return 0
+	}
+
+	return httpStatus(ErrorCode(err))
+}
+
+// mappedStatus returns the HTTPStatus that a registered code maps onto,
+// or the code itself if it is not registered (or has no mapping).
+func mappedStatus(code int) int {
+
+	if info, ok := LookupCode(code); ok && info.HTTPStatus != 0 {
+		return info.HTTPStatus
+	}
+
+	return code
+}
+
+// codeRetryable returns TRUE if a code is registered as Retryable.  Codes that
+// do not decide for themselves use the status that they map onto.
+func codeRetryable(code int) bool {
+
+	if info, ok := LookupCode(code); ok && info.Retryable != nil {
+		return *info.Retryable
+	}
+
+	if status := mappedStatus(code); status != code {
+		if info, ok := LookupCode(status); ok && info.Retryable != nil {
+			return *info.Retryable
+		}
+	}
+
+	return false
+}
+
+// clone returns a copy of a CodeInfo that does not share its Retryable pointer,
+// so that callers cannot change the registry without calling RegisterCode.
+func (info CodeInfo) clone() CodeInfo {
+
+	if info.Retryable != nil {
+		info.Retryable = boolPointer(*info.Retryable)
+	}
+
+	return info
+}
+
+// boolPointer returns a pointer to a new copy of a bool.
+func boolPointer(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,183 @@
+package derp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useCode registers an error code for the duration of a test.
+func useCode(t *testing.T, info CodeInfo) {
+
+	previous, existed := LookupCode(info.Code)
+	require.Nil(t, RegisterCode(info))
+
+	t.Cleanup(func() {
+
+		UnregisterCode(info.Code)
+
+		if existed {
+			require.Nil(t, RegisterCode(previous))
+		}
+	})
+}
+
+func TestCodeRegistry_BuiltIn(t *testing.T) {
+
+	// Every code used by derp's own constructors is registered
+	for _, code := range []int{400, 401, 403, 404, 409, 410, 418, 421, 422, 429, 499, 500, 501, 502, 503, 504, 524} {
+		info, ok := LookupCode(code)
+		require.True(t, ok, code)
+		require.NotEmpty(t, info.Name, code)
+		require.NotEmpty(t, info.Message, code)
+
+		byName, ok := LookupCodeName(info.Name)
+		require.True(t, ok, info.Name)
+		require.Equal(t, info, byName)
+	}
+
+	registered := RegisteredCodes()
+	require.Equal(t, 400, registered[0].Code)
+	require.Equal(t, 524, registered[len(registered)-1].Code)
+
+	_, ok := LookupCode(10042)
+	require.False(t, ok)
+
+	_, ok = LookupCodeName("unknown")
+	require.False(t, ok)
+}
+
+func TestCodeRegistry_Register(t *testing.T) {
+
+	useCode(t, CodeInfo{Code: 10042, Name: "billing.card_declined", HTTPStatus: 402, Message: "Your card was declined"})
+
+	info, ok := LookupCodeName("billing.card_declined")
+	require.True(t, ok)
+	require.Equal(t, 10042, info.Code)
+
+	// Names must be unique, and codes and names are required
+	require.True(t, IsConflict(RegisterCode(CodeInfo{Code: 10043, Name: "billing.card_declined"})))
+	require.True(t, IsValidationError(RegisterCode(CodeInfo{Name: "missing.code"})))
+	require.True(t, IsValidationError(RegisterCode(CodeInfo{Code: 10043})))
+	require.True(t, IsValidationError(RegisterCode(CodeInfo{Code: 10043, Name: "bad.status", HTTPStatus: 1000})))
+
+	// Re-registering a code replaces it, and releases its previous name
+	useCode(t, CodeInfo{Code: 10042, Name: "billing.declined", HTTPStatus: 402})
+
+	_, ok = LookupCodeName("billing.card_declined")
+	require.False(t, ok)
+}
+
+func TestCodeRegistry_Unregister(t *testing.T) {
+
+	require.Nil(t, RegisterCode(CodeInfo{Code: 10044, Name: "billing.expired"}))
+
+	require.True(t, UnregisterCode(10044))
+	require.False(t, UnregisterCode(10044))
+
+	_, ok := LookupCode(10044)
+	require.False(t, ok)
+
+	_, ok = LookupCodeName("billing.expired")
+	require.False(t, ok)
+}
+
+func TestCodeRegistry_Override(t *testing.T) {
+
+	useCode(t, CodeInfo{Code: 404, Name: "not_found", Message: "We looked everywhere", URL: "https://example.com/errors/404"})
+
+	err := NotFound("location", "developer text")
+	require.Equal(t, "We looked everywhere", PublicMessage(err, nil, ""))
+	require.Equal(t, "https://example.com/errors/404", NewProblem(err, nil, "").Type)
+}
+
+func TestCodeRegistry_Classification(t *testing.T) {
+
+	useCode(t, CodeInfo{Code: 10042, Name: "billing.card_declined", HTTPStatus: 402})
+	useCode(t, CodeInfo{Code: 10043, Name: "billing.missing", HTTPStatus: 404})
+	useCode(t, CodeInfo{Code: 20001, Name: "ledger.locked", HTTPStatus: 503})
+
+	declined := newError(10042, "location", "developer text")
+	missing := newError(10043, "location", "developer text")
+	locked := newError(20001, "location", "developer text")
+	unregistered := newError(30001, "location", "developer text")
+
+	require.Equal(t, "billing.card_declined", CodeName(declined))
+	require.Equal(t, "", CodeName(unregistered))
+	require.Equal(t, "", CodeName(nil))
+
+	require.Equal(t, 402, HTTPStatus(declined))
+	require.Equal(t, 503, HTTPStatus(locked))
+	require.Equal(t, 500, HTTPStatus(unregistered))
+	require.Equal(t, 0, HTTPStatus(nil))
+
+	require.True(t, IsNotFound(missing))
+	require.True(t, IsNotFoundOrGone(missing))
+	require.False(t, IsNotFound(declined))
+	require.False(t, IsInternalServerError(unregistered))
+
+	require.True(t, IsClientError(declined))
+	require.False(t, IsServerError(declined))
+	require.True(t, IsServerError(locked))
+	require.False(t, IsClientError(unregistered))
+	require.False(t, IsServerError(unregistered))
+
+	require.Equal(t, SeverityWarning, ErrorSeverity(declined))
+	require.Equal(t, SeverityInfo, ErrorSeverity(missing))
+	require.Equal(t, SeverityError, ErrorSeverity(locked))
+
+	// Codes that map onto a retryable status are retried, even through standard wrappers
+	require.True(t, IsRetryable(fmt.Errorf("wrapped: %w", ledgerError{})))
+	require.True(t, IsRetryable(locked))
+	require.False(t, IsRetryable(declined))
+	require.False(t, IsRetryable(errors.New("plain")))
+
+	// Codes without a message use the message of the status they map onto
+	require.Equal(t, "Payment Required", PublicMessage(declined, nil, ""))
+	require.Equal(t, "Not found", PublicMessage(missing, nil, ""))
+	require.Equal(t, "Temporarily unavailable.  Please try again later", PublicMessage(locked, nil, ""))
+	require.Equal(t, "Something went wrong", PublicMessage(unregistered, nil, ""))
+}
+
+func TestCodeRegistry_Retryable(t *testing.T) {
+
+	// Changing the message of a built-in code keeps its retry decision
+	useCode(t, CodeInfo{Code: 503, Name: "service_unavailable", Message: "Down for maintenance"})
+	require.True(t, IsRetryable(newError(503, "location", "developer text")))
+
+	info, _ := LookupCode(503)
+	require.Equal(t, "Down for maintenance", info.Message)
+	require.True(t, *info.Retryable)
+
+	// Unless it is explicitly overridden
+	useCode(t, CodeInfo{Code: 503, Name: "service_unavailable", Retryable: boolPointer(false)})
+	require.False(t, IsRetryable(newError(503, "location", "developer text")))
+
+	// Application codes decide for themselves, before the status that they map onto
+	useCode(t, CodeInfo{Code: 20002, Name: "ledger.closed", HTTPStatus: 502, Retryable: boolPointer(false)})
+	useCode(t, CodeInfo{Code: 20003, Name: "ledger.busy", HTTPStatus: 409, Retryable: boolPointer(true)})
+	require.False(t, IsRetryable(newError(20002, "location", "developer text")))
+	require.True(t, IsRetryable(newError(20003, "location", "developer text")))
+
+	// Lookups return copies, which cannot change the registry
+	info, _ = LookupCode(20003)
+	*info.Retryable = false
+	require.True(t, IsRetryable(newError(20003, "location", "developer text")))
+}
+
+func TestCodeRegistry_HTTPStatusGetter(t *testing.T) {
+
+	useCode(t, CodeInfo{Code: 10042, Name: "billing.card_declined", HTTPStatus: 402})
+
+	var getter HTTPStatusGetter = newError(10042, "location", "developer text")
+	require.Equal(t, 402, getter.GetHTTPStatus())
+	require.Equal(t, 404, NotFound("location", "developer text").GetHTTPStatus())
+}
+
+// ledgerError is a non-derp error that carries a registered application code.
+type ledgerError struct{}
+
+func (ledgerError) Error() string     { return "ledger is locked" }
+func (ledgerError) GetErrorCode() int { return 20001 }
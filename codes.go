@@ -65,7 +65,21 @@ const (
 	// https://www.rfc-editor.org/rfc/rfc9110.html#name-502-bad-gateway
 	codeBadGatewayError = 502
 
+	// codeServiceUnavailableError indicates that the server is temporarily unable to handle the request,
+	// such as when it is overloaded or down for maintenance.
+	// https://www.rfc-editor.org/rfc/rfc9110.html#name-503-service-unavailable
+	codeServiceUnavailableError = 503
+
+	// codeGatewayTimeoutError indicates that an upstream server did not respond in time.
+	// https://www.rfc-editor.org/rfc/rfc9110.html#name-504-gateway-timeout
+	codeGatewayTimeoutError = 504
+
 	// codeTimeout is an unofficial server error, used by derp to indicate an internal timeout.
 	// https://http.dev/524
 	codeTimeout = 524
 )
+
+// CodeClientClosedRequest is an unofficial client error, used by derp to indicate that the
+// caller abandoned an operation (such as by canceling its context) before it completed.
+// https://http.dev/499
+const CodeClientClosedRequest = 499
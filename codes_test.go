@@ -24,5 +24,7 @@ func TestCodeConstants(t *testing.T) {
 	assert.Equal(t, 500, codeInternalError)
 	assert.Equal(t, 501, codeNotImplementedError)
 	assert.Equal(t, 502, codeBadGatewayError)
+	assert.Equal(t, 503, codeServiceUnavailableError)
+	assert.Equal(t, 504, codeGatewayTimeoutError)
 	assert.Equal(t, 524, codeTimeout)
 }
@@ -0,0 +1,420 @@
+package derp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benpate/derp/plugins"
+)
+
+// ReporterFactory creates a Reporter from the "options" of one entry in a
+// configuration file.  Options are always passed as JSON (even when the file
+// is YAML) so that factories can decode them into their own struct.
+type ReporterFactory func(options json.RawMessage) (Reporter, error)
+
+// ConfigDecoder converts the content of a configuration file into JSON, so that
+// LoadConfig can read formats other than JSON.  The github.com/benpate/derp/yaml
+// module registers one for YAML files, which keeps derp itself free of dependencies.
+type ConfigDecoder func(content []byte) ([]byte, error)
+
+// Config describes a complete list of reporters, as read from a configuration file:
+//
+//	{"reporters": [
+//		{"type": "json", "minSeverity": "warning"},
+//		{"type": "syslog", "options": {"network": "udp", "address": "logs:514"}, "tags": ["billing"], "timeout": "2s"}
+//	]}
+type Config struct {
+	Reporters []ReporterConfig `json:"reporters"`
+}
+
+// ReporterConfig describes a single reporter, and the filters that are applied to it.
+type ReporterConfig struct {
+	Type        string          `json:"type"`                  // Name of a registered ReporterFactory, such as "json"
+	Options     json.RawMessage `json:"options,omitempty"`     // Passed to the ReporterFactory as-is
+	MinSeverity string          `json:"minSeverity,omitempty"` // Errors below this severity are dropped (see FilterBySeverity)
+	Tags        []string        `json:"tags,omitempty"`        // Errors without any of these tags are dropped (see FilterByTag)
+	Timeout     string          `json:"timeout,omitempty"`     // Limit for each Report call, such as "2s" (see TimeoutReporter)
+}
+
+// reporterFactories contains every registered ReporterFactory, by name.
+var reporterFactories = map[string]ReporterFactory{
+	"json":   newJSONReporter,
+	"slog":   newSlogReporter,
+	"syslog": newSyslogReporter,
+}
+
+// reporterFactoriesLock guards reporterFactories.
+var reporterFactoriesLock sync.RWMutex
+
+// configDecoders contains every registered ConfigDecoder, by file extension (such as ".yaml").
+var configDecoders = map[string]ConfigDecoder{}
+
+// configDecodersLock guards configDecoders.
+var configDecodersLock sync.RWMutex
+
+// RegisterReporter adds a ReporterFactory that configuration files can refer to by name.
+// Registering an existing name replaces the previous factory.  Names are not case sensitive.
+func RegisterReporter(name string, factory ReporterFactory) {
+
+	reporterFactoriesLock.Lock()
+	defer reporterFactoriesLock.Unlock()
+
+	reporterFactories[strings.ToLower(name)] = factory
+}
+
+// reporterFactory returns the ReporterFactory registered with the provided name.
+func reporterFactory(name string) (ReporterFactory, bool) {
+
+	reporterFactoriesLock.RLock()
+	defer reporterFactoriesLock.RUnlock()
+
+	factory, ok := reporterFactories[strings.ToLower(name)]
+	return factory, ok
+}
+
+// RegisterConfigFormat adds a ConfigDecoder for configuration files with the provided
+// extension, such as ".yaml".  Files with any other extension are read as JSON.
+// Registering an existing extension replaces the previous decoder.  Extensions
+// are not case sensitive, and the leading "." is optional.
+func RegisterConfigFormat(extension string, decoder ConfigDecoder) {
+
+	configDecodersLock.Lock()
+	defer configDecodersLock.Unlock()
+
+	configDecoders[configExtension(extension)] = decoder
+}
+
+// configDecoder returns the ConfigDecoder registered for the extension of a filename.
+func configDecoder(filename string) (ConfigDecoder, bool) {
+
+	configDecodersLock.RLock()
+	defer configDecodersLock.RUnlock()
+
+	decoder, ok := configDecoders[configExtension(filepath.Ext(filename))]
+	return decoder, ok
+}
+
+// configExtension normalizes a file extension into the form ".yaml".
+func configExtension(extension string) string {
+	return "." + strings.TrimPrefix(strings.ToLower(extension), ".")
+}
+
+/******************************************
+ * Reading Configuration
+ ******************************************/
+
+// ParseConfig reads a Config from JSON content.
+func ParseConfig(content []byte) (Config, error) {
+
+	var result Config
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&result); err != nil {
+		return result, Internal("derp.ParseConfig", "Unable to parse configuration", WithWrappedValue(err))
+	}
+
+	return result, nil
+}
+
+// LoadConfig reads a Config from a file.  Files are read as JSON, unless a
+// ConfigDecoder is registered for their extension (see RegisterConfigFormat).
+func LoadConfig(filename string) (Config, error) {
+
+	const location = "derp.LoadConfig"
+
+	content, err := os.ReadFile(filename)
+
+	if err != nil {
+		return Config{}, Internal(location, "Unable to read configuration file", filename, WithWrappedValue(err))
+	}
+
+	// Other formats are converted to JSON first, so that every format shares the same
+	// field names, and so that reporter options always reach their factory as JSON.
+	if decoder, ok := configDecoder(filename); ok {
+		if content, err = decoder(content); err != nil {
+			return Config{}, Internal(location, "Unable to convert configuration file", filename, WithWrappedValue(err))
+		}
+	}
+
+	result, err := ParseConfig(content)
+
+	if err != nil {
+		return result, Wrap(err, location, "Unable to load configuration file", filename)
+	}
+
+	return result, nil
+}
+
+/******************************************
+ * Building Reporters
+ ******************************************/
+
+// Build creates every reporter in the Config, wrapped in its filters.  If any
+// reporter cannot be created, then an error is returned and no reporters are.
+func (config Config) Build() ([]Reporter, error) {
+
+	result := make([]Reporter, 0, len(config.Reporters))
+
+	for index, reporterConfig := range config.Reporters {
+
+		reporter, err := reporterConfig.Reporter()
+
+		if err != nil {
+			return nil, Wrap(err, "derp.Config.Build", "Unable to create reporter", WithField("index", index))
+		}
+
+		result = append(result, reporter)
+	}
+
+	return result, nil
+}
+
+// Reporter creates the configured reporter, wrapped in its filters.
+func (config ReporterConfig) Reporter() (Reporter, error) {
+
+	const location = "derp.ReporterConfig.Reporter"
+
+	factory, ok := reporterFactory(config.Type)
+
+	if !ok {
+		return nil, Internal(location, "Unknown reporter type", config.Type)
+	}
+
+	minSeverity, err := ParseSeverity(config.MinSeverity)
+
+	if err != nil {
+		return nil, Wrap(err, location, "Invalid minSeverity", config.Type)
+	}
+
+	var timeout time.Duration
+
+	if config.Timeout != "" {
+		if timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, Internal(location, "Invalid timeout", config.Type, config.Timeout, WithWrappedValue(err))
+		}
+	}
+
+	result, err := factory(config.Options)
+
+	if err != nil {
+		return nil, Wrap(err, location, "Unable to create reporter", config.Type)
+	}
+
+	if result == nil {
+		return nil, Internal(location, "Reporter factory returned nil", config.Type)
+	}
+
+	// RULE: Filters go outside the timeout, so that dropped errors never start a goroutine.
+	if timeout > 0 {
+		result = TimeoutReporter(timeout, result)
+	}
+
+	if len(config.Tags) > 0 {
+		result = FilterByTag(result, config.Tags...)
+	}
+
+	if minSeverity != SeverityUnset {
+		result = FilterBySeverity(minSeverity, result)
+	}
+
+	return result, nil
+}
+
+/******************************************
+ * Applying Configuration
+ ******************************************/
+
+// ConfigurePlugins replaces the global reporter list with the reporters described
+// in a configuration file (see LoadConfig).  If the file cannot be used, the current list is kept.
+// Replaced reporters that implement io.Closer (such as plugins.Syslog) are closed, without waiting
+// for deliveries that are still running, so closable reporters should ignore errors reported after Close.
+func ConfigurePlugins(filename string) error {
+
+	const location = "derp.ConfigurePlugins"
+
+	config, err := LoadConfig(filename)
+
+	if err != nil {
+		return Wrap(err, location, "Unable to load configuration")
+	}
+
+	reporters, err := config.Build()
+
+	if err != nil {
+		return Wrap(err, location, "Unable to create reporters", filename)
+	}
+
+	// RULE: Close replaced reporters only after the swap, so that no new Report reaches them.  Deliveries
+	// that are still running (see TimeoutReporter) may call them after Close, which plugins.Syslog ignores.
+	for _, reporter := range Plugins.swap(reporters...) {
+		if err := closeReporter(reporter); err != nil {
+			Report(Wrap(err, location, "Unable to close replaced reporter", reporterName(reporter)))
+		}
+	}
+
+	return nil
+}
+
+// closeReporter closes a reporter that implements io.Closer,
+// looking through the filters and timeouts that Build wraps around it.
+func closeReporter(reporter Reporter) error {
+
+	switch wrapper := reporter.(type) {
+
+	case wrappingReporter:
+		return closeReporter(wrapper.innerReporter())
+
+	case io.Closer:
+		return wrapper.Close()
+	}
+
+	return nil
+}
+
+// WatchConfig calls ConfigurePlugins now, and again every time the file changes, until the
+// context is canceled.  The file is checked once per interval.  If a changed file cannot be
+// used, the error is reported (to the reporters that are still in place) and the current
+// list is kept.  Only the first load returns an error.
+func WatchConfig(ctx context.Context, filename string, interval time.Duration) error {
+
+	if interval <= 0 {
+		return Validation("Interval must be greater than zero", filename, interval.String(), WithLocation("derp.WatchConfig"))
+	}
+
+	if err := ConfigurePlugins(filename); err != nil {
+		return Wrap(err, "derp.WatchConfig", "Unable to configure plugins")
+	}
+
+	// Read before the goroutine starts, so that changes made after WatchConfig returns are always noticed.
+	current := readConfigVersion(filename)
+
+	go func() {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+
+				version := readConfigVersion(filename)
+
+				if version == current {
+					continue
+				}
+
+				current = version
+
+				if err := ConfigurePlugins(filename); err != nil {
+					Report(Wrap(err, "derp.WatchConfig", "Unable to reload configuration", filename))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// configVersion identifies one revision of a configuration file.
+type configVersion struct {
+	modTime int64
+	size    int64
+}
+
+// readConfigVersion returns the current revision of the file, or
+// the zero value if the file cannot be read.
+func readConfigVersion(filename string) configVersion {
+
+	info, err := os.Stat(filename)
+
+	if err != nil {
+		return configVersion{}
+	}
+
+	return configVersion{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
+
+/******************************************
+ * Built-In Reporter Factories
+ ******************************************/
+
+// decodeOptions reads reporter options into a struct, rejecting unknown fields
+// so that typos in a configuration file are not silently ignored.
+func decodeOptions(options json.RawMessage, value any) error {
+
+	if len(options) == 0 || string(options) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(options))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(value); err != nil {
+		return Internal("derp.decodeOptions", "Unable to parse reporter options", WithWrappedValue(err))
+	}
+
+	return nil
+}
+
+// newJSONReporter creates a plugins.JSON reporter, which has no options.
+func newJSONReporter(options json.RawMessage) (Reporter, error) {
+	return plugins.JSON{}, decodeOptions(options, &struct{}{})
+}
+
+// newSlogReporter creates a plugins.Slog reporter that writes to slog.Default(), which has no options.
+func newSlogReporter(options json.RawMessage) (Reporter, error) {
+	return plugins.Slog{}, decodeOptions(options, &struct{}{})
+}
+
+// newSyslogReporter creates a plugins.Syslog reporter.
+func newSyslogReporter(options json.RawMessage) (Reporter, error) {
+
+	const location = "derp.newSyslogReporter"
+
+	var values struct {
+		Network  string `json:"network"`
+		Address  string `json:"address"`
+		Facility *int   `json:"facility"`
+		AppName  string `json:"appName"`
+		Hostname string `json:"hostname"`
+		Timeout  string `json:"timeout"`
+	}
+
+	if err := decodeOptions(options, &values); err != nil {
+		return nil, Wrap(err, location, "Invalid syslog options")
+	}
+
+	result := plugins.NewSyslog(values.Network, values.Address)
+	result.AppName = values.AppName
+	result.Hostname = values.Hostname
+
+	if values.Facility != nil {
+		result.Facility = *values.Facility
+	}
+
+	if values.Timeout != "" {
+
+		timeout, err := time.ParseDuration(values.Timeout)
+
+		if err != nil {
+			return nil, Internal(location, "Invalid syslog timeout", values.Timeout, WithWrappedValue(err))
+		}
+
+		result.Timeout = timeout
+	}
+
+	return result, nil
+}
@@ -0,0 +1,323 @@
+package derp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benpate/derp/plugins"
+	"github.com/stretchr/testify/require"
+)
+
+// usePlugins restores the global reporter list when the test ends.
+func usePlugins(t *testing.T) {
+	previous := Plugins.Reporters()
+	t.Cleanup(func() { SetPlugins(previous...) })
+}
+
+// registerRecorder registers a "recorder" reporter type that always returns
+// the same plugin, and returns that plugin.
+func registerRecorder(t *testing.T) *lockedRecordingPlugin {
+
+	plugin := &lockedRecordingPlugin{}
+
+	RegisterReporter("Recorder", func(options json.RawMessage) (Reporter, error) {
+		return plugin, decodeOptions(options, &struct{}{})
+	})
+
+	t.Cleanup(func() {
+		reporterFactoriesLock.Lock()
+		defer reporterFactoriesLock.Unlock()
+		delete(reporterFactories, "recorder")
+	})
+
+	return plugin
+}
+
+func TestParseConfig(t *testing.T) {
+
+	expected := Config{
+		Reporters: []ReporterConfig{
+			{Type: "json", MinSeverity: "warning"},
+			{Type: "syslog", Options: json.RawMessage(`{"address":"logs:514","network":"udp"}`), Tags: []string{"billing"}, Timeout: "2s"},
+		},
+	}
+
+	fromJSON, err := ParseConfig([]byte(`{"reporters": [
+		{"type": "json", "minSeverity": "warning"},
+		{"type": "syslog", "options": {"address":"logs:514","network":"udp"}, "tags": ["billing"], "timeout": "2s"}
+	]}`))
+
+	require.Nil(t, err)
+	require.Equal(t, expected, fromJSON)
+}
+
+func TestParseConfig_Errors(t *testing.T) {
+
+	_, err := ParseConfig([]byte(`{"reporters": [{"kind": "json"}]}`))
+	require.Error(t, err)
+
+	_, err = ParseConfig([]byte("reporters: [unclosed"))
+	require.Error(t, err)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestLoadConfig_Format(t *testing.T) {
+
+	registerRecorder(t)
+
+	// A test format, where each line is the type of one reporter
+	RegisterConfigFormat("LINES", func(content []byte) ([]byte, error) {
+
+		var config Config
+
+		for _, line := range strings.Fields(string(content)) {
+			if line == "invalid" {
+				return nil, errors.New("invalid line")
+			}
+			config.Reporters = append(config.Reporters, ReporterConfig{Type: line})
+		}
+
+		return json.Marshal(config)
+	})
+
+	t.Cleanup(func() {
+		configDecodersLock.Lock()
+		defer configDecodersLock.Unlock()
+		delete(configDecoders, ".lines")
+	})
+
+	directory := t.TempDir()
+
+	filename := filepath.Join(directory, "derp.lines")
+	require.Nil(t, os.WriteFile(filename, []byte("json\nrecorder\n"), 0o600))
+
+	config, err := LoadConfig(filename)
+	require.Nil(t, err)
+	require.Equal(t, Config{Reporters: []ReporterConfig{{Type: "json"}, {Type: "recorder"}}}, config)
+
+	// Decoding errors are returned
+	require.Nil(t, os.WriteFile(filename, []byte("invalid"), 0o600))
+	_, err = LoadConfig(filename)
+	require.Error(t, err)
+
+	// Other extensions are still read as JSON
+	filename = filepath.Join(directory, "derp.conf")
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "json"}]}`), 0o600))
+
+	config, err = LoadConfig(filename)
+	require.Nil(t, err)
+	require.Equal(t, Config{Reporters: []ReporterConfig{{Type: "json"}}}, config)
+}
+
+func TestConfig_Build(t *testing.T) {
+
+	plugin := registerRecorder(t)
+
+	config, err := ParseConfig([]byte(`{"reporters": [
+		{"type": "recorder"},
+		{"type": "RECORDER", "minSeverity": "error", "tags": ["billing"], "timeout": "1s"}
+	]}`))
+	require.Nil(t, err)
+
+	reporters, err := config.Build()
+	require.Nil(t, err)
+	require.Len(t, reporters, 2)
+
+	// The first reporter is unfiltered
+	require.Same(t, plugin, reporters[0])
+
+	// The second reporter needs both the severity and the tag
+	filtered := reporters[1]
+	filtered.Report(NotFound("test", "Not billing"))
+	filtered.Report(Internal("test", "Not billing"))
+	filtered.Report(NotFound("test", "Too minor", WithTags("billing")))
+	require.Equal(t, 0, len(plugin.recorded()))
+
+	filtered.Report(Internal("test", "Reported", WithTags("billing")))
+	require.Equal(t, 1, len(plugin.recorded()))
+}
+
+func TestConfig_BuildErrors(t *testing.T) {
+
+	registerRecorder(t)
+
+	for _, content := range []string{
+		`{"reporters": [{"type": "unknown"}]}`,
+		`{"reporters": [{"type": "recorder", "minSeverity": "extreme"}]}`,
+		`{"reporters": [{"type": "recorder", "timeout": "soon"}]}`,
+		`{"reporters": [{"type": "recorder", "options": {"unknown": true}}]}`,
+		`{"reporters": [{"type": "recorder"}, {"type": "syslog", "options": {"timeout": "soon"}}]}`,
+	} {
+		config, err := ParseConfig([]byte(content))
+		require.Nil(t, err, content)
+
+		reporters, err := config.Build()
+		require.Error(t, err, content)
+		require.Nil(t, reporters, content)
+	}
+}
+
+func TestConfig_BuiltIn(t *testing.T) {
+
+	config, err := ParseConfig([]byte(`{"reporters": [
+		{"type": "json"},
+		{"type": "slog"},
+		{"type": "syslog", "options": {"network": "udp", "address": "127.0.0.1:514", "facility": 16, "appName": "app", "timeout": "1s"}}
+	]}`))
+	require.Nil(t, err)
+
+	reporters, err := config.Build()
+	require.Nil(t, err)
+	require.Equal(t, plugins.JSON{}, reporters[0])
+	require.Equal(t, plugins.Slog{}, reporters[1])
+
+	syslog, ok := reporters[2].(*plugins.Syslog)
+	require.True(t, ok)
+	require.Equal(t, "udp", syslog.Network)
+	require.Equal(t, "127.0.0.1:514", syslog.Address)
+	require.Equal(t, plugins.SyslogFacilityLocal0, syslog.Facility)
+	require.Equal(t, "app", syslog.AppName)
+	require.Equal(t, time.Second, syslog.Timeout)
+}
+
+func TestConfigurePlugins(t *testing.T) {
+
+	usePlugins(t)
+	plugin := registerRecorder(t)
+
+	filename := filepath.Join(t.TempDir(), "derp.json")
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "recorder"}]}`), 0o600))
+	require.Nil(t, ConfigurePlugins(filename))
+	require.Equal(t, []Reporter{plugin}, Plugins.Reporters())
+
+	// Invalid files keep the current list
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "unknown"}]}`), 0o600))
+	require.Error(t, ConfigurePlugins(filename))
+	require.Equal(t, []Reporter{plugin}, Plugins.Reporters())
+}
+
+func TestConfig_BuildStats(t *testing.T) {
+
+	useFallback(t)
+
+	blocked := blockingPlugin{release: make(chan struct{})}
+	t.Cleanup(func() { close(blocked.release) })
+
+	RegisterReporter("blocked", func(options json.RawMessage) (Reporter, error) {
+		return blocked, nil
+	})
+
+	t.Cleanup(func() {
+		reporterFactoriesLock.Lock()
+		defer reporterFactoriesLock.Unlock()
+		delete(reporterFactories, "blocked")
+	})
+
+	config, err := ParseConfig([]byte(`{"reporters": [{"type": "blocked", "minSeverity": "error", "tags": ["billing"], "timeout": "1ms"}]}`))
+	require.Nil(t, err)
+
+	reporters, err := config.Build()
+	require.Nil(t, err)
+
+	var list ReporterList
+	list.Set(reporters...)
+	report(&list, Internal("test", "Reported", WithTags("billing")))
+
+	// Timeouts inside of filters are counted, under the name of the configured reporter
+	stats := list.Stats()[0]
+	require.Equal(t, "derp.blockingPlugin", stats.Name)
+	require.Equal(t, int64(1), stats.Timeouts)
+	require.Equal(t, int64(0), stats.Successes)
+}
+
+// closingReporter is a Reporter that records whether it has been closed.
+type closingReporter struct {
+	closed bool
+}
+
+func (reporter *closingReporter) Report(error) {}
+
+func (reporter *closingReporter) Close() error {
+	reporter.closed = true
+	return nil
+}
+
+func TestConfigurePlugins_ClosesReplaced(t *testing.T) {
+
+	usePlugins(t)
+
+	var created []*closingReporter
+
+	RegisterReporter("closer", func(options json.RawMessage) (Reporter, error) {
+		reporter := &closingReporter{}
+		created = append(created, reporter)
+		return reporter, nil
+	})
+
+	t.Cleanup(func() {
+		reporterFactoriesLock.Lock()
+		defer reporterFactoriesLock.Unlock()
+		delete(reporterFactories, "closer")
+	})
+
+	filename := filepath.Join(t.TempDir(), "derp.json")
+	content := []byte(`{"reporters": [{"type": "closer", "minSeverity": "warning", "tags": ["billing"], "timeout": "1s"}]}`)
+	require.Nil(t, os.WriteFile(filename, content, 0o600))
+
+	// Replaced reporters are closed, even when they are wrapped in filters
+	require.Nil(t, ConfigurePlugins(filename))
+	require.Nil(t, ConfigurePlugins(filename))
+	require.Len(t, created, 2)
+	require.True(t, created[0].closed)
+	require.False(t, created[1].closed)
+}
+
+func TestWatchConfig(t *testing.T) {
+
+	usePlugins(t)
+	plugin := registerRecorder(t)
+
+	filename := filepath.Join(t.TempDir(), "derp.json")
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "recorder"}]}`), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.Nil(t, WatchConfig(ctx, filename, 5*time.Millisecond))
+	require.Equal(t, 1, Plugins.Len())
+
+	// An invalid change is reported to the current reporters, which are kept
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "unknown"}]}`), 0o600))
+	require.Eventually(t, func() bool { return len(plugin.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, 1, Plugins.Len())
+
+	// A valid change replaces them
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "recorder"}, {"type": "recorder", "minSeverity": "critical"}]}`), 0o600))
+	require.Eventually(t, func() bool { return Plugins.Len() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestWatchConfig_Missing(t *testing.T) {
+	require.Error(t, WatchConfig(context.Background(), filepath.Join(t.TempDir(), "missing.json"), time.Second))
+}
+
+func TestWatchConfig_Interval(t *testing.T) {
+
+	usePlugins(t)
+	registerRecorder(t)
+
+	filename := filepath.Join(t.TempDir(), "derp.json")
+	require.Nil(t, os.WriteFile(filename, []byte(`{"reporters": [{"type": "recorder"}]}`), 0o600))
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		err := WatchConfig(context.Background(), filename, interval)
+		require.True(t, IsValidationError(err))
+	}
+}
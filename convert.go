@@ -1,7 +1,5 @@
 package derp
 
-import "time"
-
 // AsError converts a generic error into a derp.Error type
 func AsError(err error) Error {
 
@@ -29,7 +27,7 @@ func AsError(err error) Error {
 			WrappedValue: err,
 			Location:     "derp.AsError",
 			Code:         codeInternalError,
-			TimeStamp:    time.Now().Unix(),
+			TimeStamp:    Now(),
 		}
 	}
 }
@@ -10,6 +10,12 @@ import (
  * New Derp
  ******************************************/
 
+// New returns an error with any error code, including application codes
+// (see RegisterCode) that have no constructor of their own.
+func New(code int, location string, message string, details ...any) Error {
+	return newError(code, location, message, details...)
+}
+
 // BadRequest returns a (400) Bad Request error
 // which indicates that the request is not properly formatted.
 // https://www.rfc-editor.org/rfc/rfc9110.html#name-400-bad-request
@@ -186,7 +192,8 @@ func newError(code int, location string, message string, details ...any) Error {
 		Code:      code,
 		Message:   message,
 		Details:   make([]any, 0, len(details)),
-		TimeStamp: time.Now().Unix(),
+		TimeStamp: Now(),
+		ID:        newErrorID(nil),
 	}
 
 	for _, detail := range details {
@@ -229,7 +236,7 @@ func ErrorCode(err error) int {
 		return 0
 	}
 
-	if getter, ok := err.(ErrorCodeGetter); ok {
+	if getter, ok := AsType[ErrorCodeGetter](err); ok {
 		return getter.GetErrorCode()
 	}
 
@@ -259,7 +266,7 @@ func RetryAfter(err error) time.Duration {
 		return 0
 	}
 
-	if getter, ok := err.(RetryAfterGetter); ok {
+	if getter, ok := AsType[RetryAfterGetter](err); ok {
 		return getter.GetRetryAfter()
 	}
 
@@ -296,7 +303,41 @@ func Details(err error) []any {
 	return nil
 }
 
+// AllFields retrieves the typed fields of any error, merged across the whole chain of
+// wrapped errors.  Outer fields override inner fields with the same key.  The result is
+// a new map that the caller may modify, or nil if the error has no fields.
+func AllFields(err error) map[string]any {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return nil
+	}
+
+	if getter, ok := AsType[FieldsGetter](err); ok {
+		return copyFields(getter.GetFields())
+	}
+
+	return nil
+}
+
+// copyFields returns a shallow copy of a fields map, or nil if it is empty.
+func copyFields(fields map[string]any) map[string]any {
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	result := make(map[string]any, len(fields))
+
+	for key, value := range fields {
+		result[key] = value
+	}
+
+	return result
+}
+
 // Serialize converts any error into its JSON string representation.
+// Sensitive values are removed by the current Redactor (see SetRedactor).
 func Serialize(err error) string {
 
 	// double nil check to make nilaway happy
@@ -1,6 +1,8 @@
 package derp
 
 import (
+	"errors"
+	"net"
 	"reflect"
 	"strings"
 	"time"
@@ -9,33 +11,35 @@ import (
 /******************************************
  * Error Code Functions
  * These determine if an error matches a specific error code.
+ * Registered application codes match the HTTP
+ * status that they map onto.
  *****************************************/
 
 // IsBadRequest returns TRUE if this is a 400 (Bad Request) error.
 func IsBadRequest(err error) bool {
-	return ErrorCode(err) == codeBadRequestError
+	return mappedStatus(ErrorCode(err)) == codeBadRequestError
 }
 
 // IsUnauthorized returns TRUE if this is a 401 (Unauthorized) error.
 func IsUnauthorized(err error) bool {
-	return ErrorCode(err) == codeUnauthorizedError
+	return mappedStatus(ErrorCode(err)) == codeUnauthorizedError
 }
 
 // IsForbidden returns TRUE if this is a 403 (Forbidden) error.
 func IsForbidden(err error) bool {
-	return ErrorCode(err) == codeForbiddenError
+	return mappedStatus(ErrorCode(err)) == codeForbiddenError
 }
 
 // IsConflict returns TRUE if this is a 409 (Conflict) error, such as a write that violates a
 // unique constraint.
 func IsConflict(err error) bool {
-	return ErrorCode(err) == codeConflictError
+	return mappedStatus(ErrorCode(err)) == codeConflictError
 }
 
 // IsNotFound returns TRUE if this is a 404 (Not Found) error.
 func IsNotFound(err error) bool {
 
-	if ErrorCode(err) == codeNotFoundError {
+	if mappedStatus(ErrorCode(err)) == codeNotFoundError {
 		return true
 	}
 
@@ -44,13 +48,13 @@ func IsNotFound(err error) bool {
 
 // IsGone returns TRUE if this is a 410 (Gone) error.
 func IsGone(err error) bool {
-	return (ErrorCode(err) == codeGoneError)
+	return mappedStatus(ErrorCode(err)) == codeGoneError
 }
 
 // IsNotFoundOrGone returns TRUE if this is a 404 (Not Found) or 410 (Gone) error.
 func IsNotFoundOrGone(err error) bool {
 
-	switch ErrorCode(err) {
+	switch mappedStatus(ErrorCode(err)) {
 	case codeNotFoundError, codeGoneError:
 		return true
 	}
@@ -68,17 +72,17 @@ func hasNotFoundMessage(err error) bool {
 
 // IsTeapot returns TRUE if this is a 418 (I'm a Teapot) error.
 func IsTeapot(err error) bool {
-	return ErrorCode(err) == codeTeapotError
+	return mappedStatus(ErrorCode(err)) == codeTeapotError
 }
 
 // IsMisdirectedRequest returns TRUE if this is a 421 (Misdirected Request) error.
 func IsMisdirectedRequest(err error) bool {
-	return ErrorCode(err) == codeMisdirectedRequestError
+	return mappedStatus(ErrorCode(err)) == codeMisdirectedRequestError
 }
 
 // IsValidationError returns TRUE if this is a 422 (Validation) error.
 func IsValidationError(err error) bool {
-	return ErrorCode(err) == codeValidationError
+	return mappedStatus(ErrorCode(err)) == codeValidationError
 }
 
 // IsTooManyRequests returns TRUE if this is a 429 (Too Many Requests) error.
@@ -87,7 +91,7 @@ func IsValidationError(err error) bool {
 func IsTooManyRequests(err error) (bool, time.Duration) {
 
 	// Early return if NOT a 429 (Too Many Requests) error
-	if ErrorCode(err) != codeTooManyRequestsError {
+	if mappedStatus(ErrorCode(err)) != codeTooManyRequestsError {
 		return false, 0
 	}
 
@@ -102,61 +106,100 @@ func IsTooManyRequests(err error) (bool, time.Duration) {
 
 // IsInternalServerError returns TRUE if this is a 500 (Internal Server Error) error.
 func IsInternalServerError(err error) bool {
-	return ErrorCode(err) == codeInternalError
+	return mappedStatus(ErrorCode(err)) == codeInternalError
 }
 
 // IsNotImplemented returns TRUE if this is a 501 (Not Implemented) error.
 func IsNotImplemented(err error) bool {
-	return ErrorCode(err) == codeNotImplementedError
+	return mappedStatus(ErrorCode(err)) == codeNotImplementedError
 }
 
 // IsBadGateway returns TRUE if this is a 502 (Bad Gateway) error, such as an
 // upstream server returning a malformed or contradictory response.
 func IsBadGateway(err error) bool {
-	return ErrorCode(err) == codeBadGatewayError
+	return mappedStatus(ErrorCode(err)) == codeBadGatewayError
 }
 
 /******************************************
  * Range Functions
  * These functions determine if an error is
  * within a certain range of HTTP status codes.
+ * Registered application codes are classified
+ * by the HTTP status that they map onto.
  *****************************************/
 
 // IsInformational returns TRUE if the error `Code` is a 1xx / Informational error.
 // https://en.wikipedia.org/wiki/List_of_HTTP_status_codes#1xx_informational_response
 func IsInformational(err error) bool {
-	code := ErrorCode(err)
+	code := mappedStatus(ErrorCode(err))
 	return code >= 100 && code < 200
 }
 
 // IsSuccess returns TRUE if the error `Code` is a 2xx / Success error.
 // https://en.wikipedia.org/wiki/List_of_HTTP_status_codes#2xx_success
 func IsSuccess(err error) bool {
-	code := ErrorCode(err)
+	code := mappedStatus(ErrorCode(err))
 	return code >= 200 && code < 300
 }
 
 // IsRedirection returns TRUE if the error `Code` is a 3xx / Redirection error.
 // https://en.wikipedia.org/wiki/List_of_HTTP_status_codes#3xx_redirection
 func IsRedirection(err error) bool {
-	code := ErrorCode(err)
+	code := mappedStatus(ErrorCode(err))
 	return code >= 300 && code < 400
 }
 
 // IsClientError returns TRUE if the error `Code` is a 4xx / Client Error error.
 // https://en.wikipedia.org/wiki/List_of_HTTP_status_codes#4xx_client_errors
 func IsClientError(err error) bool {
-	code := ErrorCode(err)
+	code := mappedStatus(ErrorCode(err))
 	return code >= 400 && code < 500
 }
 
 // IsServerError returns TRUE if the error `Code` is a 5xx / Server Error error.
 // https://en.wikipedia.org/wiki/List_of_HTTP_status_codes#5xx_server_errors
 func IsServerError(err error) bool {
-	code := ErrorCode(err)
+	code := mappedStatus(ErrorCode(err))
 	return code >= 500 && code < 600
 }
 
+/******************************************
+ * Retry Classification
+ * These functions determine if an error
+ * is temporary, and worth retrying.
+ *****************************************/
+
+// IsRetryable returns TRUE if the operation that caused this error is worth retrying.
+// Errors that implement RetryableGetter (including every derp.Error) decide for themselves.
+// Otherwise, temporary failures are retryable: (429) Too Many Requests, (502) Bad Gateway,
+// (503) Service Unavailable, (504) Gateway Timeout, (524) Timeout, network timeouts, and
+// any application code registered as Retryable, or that maps onto a retryable status (see RegisterCode).
+func IsRetryable(err error) bool {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return false
+	}
+
+	if getter, ok := AsType[RetryableGetter](err); ok {
+		return getter.GetRetryable()
+	}
+
+	return isRetryableDefault(err)
+}
+
+// isRetryableDefault classifies an error that has no explicit retry decision,
+// based on its registered error code (see RegisterCode) and any network timeout in its chain.
+func isRetryableDefault(err error) bool {
+
+	if codeRetryable(ErrorCode(err)) {
+		return true
+	}
+
+	var netError net.Error
+	return errors.As(err, &netError) && netError.Timeout()
+}
+
 /******************************************
  * Other Utility Functions
  *****************************************/
@@ -493,3 +493,40 @@ func FuzzIsNotFound(f *testing.F) {
 		require.Equal(t, strings.EqualFold(message, "not found"), IsNotFound(err), "message %q", message)
 	})
 }
+
+func TestIsRetryable(t *testing.T) {
+
+	// nil errors are never retried
+	require.False(t, IsRetryable(nil))
+
+	// temporary failures are retryable by default
+	require.True(t, IsRetryable(newError(codeTooManyRequestsError, "", "")))
+	require.True(t, IsRetryable(newError(codeBadGatewayError, "", "")))
+	require.True(t, IsRetryable(newError(codeServiceUnavailableError, "", "")))
+	require.True(t, IsRetryable(newError(codeGatewayTimeoutError, "", "")))
+	require.True(t, IsRetryable(Timeout("", "")))
+	require.True(t, IsRetryable(HTTPError{Response: HTTPResponseReport{StatusCode: 503}}))
+
+	// network timeouts are retryable, even when wrapped
+	require.True(t, IsRetryable(timeoutError{}))
+	require.True(t, IsRetryable(Wrap(timeoutError{}, "location", "message", WithInternalError())))
+
+	// everything else is not
+	require.False(t, IsRetryable(Internal("", "")))
+	require.False(t, IsRetryable(NotFound("", "")))
+	require.False(t, IsRetryable(errors.New("generic")))
+}
+
+func TestIsRetryable_Explicit(t *testing.T) {
+
+	// an explicit decision overrides the default for the code
+	require.True(t, IsRetryable(Internal("", "", WithRetryable(true))))
+	require.False(t, IsRetryable(BadGateway("", "", WithRetryable(false))))
+
+	// explicit decisions survive wrapping
+	inner := BadGateway("inner", "message", WithRetryable(false))
+	require.False(t, IsRetryable(Wrap(inner, "outer", "message")))
+
+	// ...unless the outer error decides otherwise
+	require.True(t, IsRetryable(Wrap(inner, "outer", "message", WithRetryable(true))))
+}
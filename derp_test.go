@@ -3,6 +3,7 @@ package derp
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"strings"
@@ -73,6 +74,11 @@ func TestNewConvenienceFns(t *testing.T) {
 
 	badGateway := BadGateway("location", "description")
 	require.Equal(t, codeBadGatewayError, ErrorCode(badGateway))
+
+	custom := New(10042, "location", "description", "detail")
+	require.Equal(t, 10042, ErrorCode(custom))
+	require.Equal(t, []any{"detail"}, custom.Details)
+	require.False(t, custom.TimeStamp.IsZero())
 }
 
 func TestMessage(t *testing.T) {
@@ -323,9 +329,10 @@ func FuzzSerialize(f *testing.F) {
 
 		// RULE: JSON replaces invalid UTF-8 with U+FFFD, so strings only
 		// round-trip exactly when they were valid UTF-8 to begin with.
+		// Messages are also scrubbed by the Redactor before they are serialized.
 		if utf8.ValidString(location) && utf8.ValidString(message) {
 			require.Equal(t, location, parsed.Location)
-			require.Equal(t, message, parsed.Message)
+			require.Equal(t, currentRedactor().Scrub(message), parsed.Message)
 		}
 	})
 }
@@ -339,3 +346,11 @@ func TestCodeGeneric(t *testing.T) {
 	err := errors.New("whatever, dude")
 	assert.Equal(t, 500, ErrorCode(err))
 }
+
+func TestCodeStandardWrapper(t *testing.T) {
+
+	// Standard wrappers carry no code, so the wrapped derp code is used
+	err := fmt.Errorf("while loading: %w", NotFound("location", "message"))
+	assert.Equal(t, 404, ErrorCode(err))
+	assert.True(t, IsNotFound(err))
+}
@@ -0,0 +1,107 @@
+package derptest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/benpate/derp"
+)
+
+// AssertCode checks that the error has the expected error code (see derp.ErrorCode).
+// Like the testify assertions, it reports a failure and returns false when the check fails.
+func AssertCode(t testing.TB, err error, expected int) bool {
+
+	t.Helper()
+
+	if derp.IsNil(err) {
+		t.Errorf("expected an error with code %d, but the error is nil", expected)
+		return false
+	}
+
+	if actual := derp.ErrorCode(err); actual != expected {
+		t.Errorf("expected error code %d, but got %d: %s", expected, actual, describe(err))
+		return false
+	}
+
+	return true
+}
+
+// AssertLocationChain checks the locations of every error in the chain, from the outermost
+// error inward.  Errors without a location (such as those created by fmt.Errorf) are skipped.
+func AssertLocationChain(t testing.TB, err error, expected ...string) bool {
+
+	t.Helper()
+
+	actual := LocationChain(err)
+
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected location chain %q, but got %q", expected, actual)
+		return false
+	}
+
+	return true
+}
+
+// AssertWraps checks that the target can be found anywhere in the error's chain (see errors.Is).
+func AssertWraps(t testing.TB, err error, target error) bool {
+
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("expected error to wrap %q, but it does not: %s", describe(target), describe(err))
+		return false
+	}
+
+	return true
+}
+
+// AssertHTTPError checks that the chain includes a derp.HTTPError,
+// and that the failed response had the expected status code.
+func AssertHTTPError(t testing.TB, err error, expectedStatusCode int) bool {
+
+	t.Helper()
+
+	httpError := derp.UnwrapHTTPError(err)
+
+	if httpError == nil {
+		t.Errorf("expected an HTTPError, but none was found: %s", describe(err))
+		return false
+	}
+
+	if actual := httpError.Response.StatusCode; actual != expectedStatusCode {
+		t.Errorf("expected HTTP status %d, but got %d: %s %s", expectedStatusCode, actual, httpError.Request.Method, httpError.Request.URL)
+		return false
+	}
+
+	return true
+}
+
+// LocationChain returns the locations of every error in the chain, from the outermost
+// error inward.  Errors without a location (such as those created by fmt.Errorf) are skipped.
+func LocationChain(err error) []string {
+
+	result := make([]string, 0)
+
+	for derp.NotNil(err) {
+
+		if location := derp.Location(err); location != "" {
+			result = append(result, location)
+		}
+
+		err = errors.Unwrap(err)
+	}
+
+	return result
+}
+
+// describe returns a short description of an error for failure messages.
+func describe(err error) string {
+
+	if derp.IsNil(err) {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("%s (%T)", err.Error(), err)
+}
@@ -0,0 +1,95 @@
+package derptest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/benpate/derp"
+)
+
+// fakeT records the failures reported by an assertion, instead of failing the real test.
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// check confirms that an assertion returned the expected result, and
+// reported a failure only when it failed.
+func check(t *testing.T, fake *fakeT, result bool, expected bool) {
+
+	t.Helper()
+
+	if result != expected {
+		t.Errorf("expected assertion to return %t, got %t", expected, result)
+	}
+
+	if failed := len(fake.failures) > 0; failed == expected {
+		t.Errorf("unexpected failures: %q", fake.failures)
+	}
+}
+
+func TestAssertCode(t *testing.T) {
+
+	err := derp.Wrap(derp.NotFound("inner", "message"), "outer", "message")
+
+	fake := &fakeT{}
+	check(t, fake, AssertCode(fake, err, 404), true)
+
+	fake = &fakeT{}
+	check(t, fake, AssertCode(fake, err, 500), false)
+
+	fake = &fakeT{}
+	check(t, fake, AssertCode(fake, nil, 404), false)
+}
+
+func TestAssertLocationChain(t *testing.T) {
+
+	inner := derp.NotFound("inner", "message")
+	err := derp.Wrap(fmt.Errorf("context: %w", inner), "outer", "message")
+
+	fake := &fakeT{}
+	check(t, fake, AssertLocationChain(fake, err, "outer", "inner"), true)
+
+	fake = &fakeT{}
+	check(t, fake, AssertLocationChain(fake, err, "outer"), false)
+
+	fake = &fakeT{}
+	check(t, fake, AssertLocationChain(fake, nil), true)
+}
+
+func TestAssertWraps(t *testing.T) {
+
+	sentinel := errors.New("sentinel")
+	err := derp.Wrap(sentinel, "location", "message")
+
+	fake := &fakeT{}
+	check(t, fake, AssertWraps(fake, err, sentinel), true)
+
+	fake = &fakeT{}
+	check(t, fake, AssertWraps(fake, err, errors.New("other")), false)
+}
+
+func TestAssertHTTPError(t *testing.T) {
+
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/users", nil)
+	response := &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Request: request}
+	err := derp.Wrap(derp.NewHTTPError(request, response), "location", "message")
+
+	fake := &fakeT{}
+	check(t, fake, AssertHTTPError(fake, err, http.StatusNotFound), true)
+
+	fake = &fakeT{}
+	check(t, fake, AssertHTTPError(fake, err, http.StatusOK), false)
+
+	fake = &fakeT{}
+	check(t, fake, AssertHTTPError(fake, derp.NotFound("location", "message"), http.StatusNotFound), false)
+}
@@ -0,0 +1,46 @@
+// Package derptest provides helpers for testing code that creates and reports derp errors:
+// a RecordingReporter that captures reported errors, assertion helpers that work with any
+// testing.TB, and UsePlugins, which swaps the global reporters for the length of a test.
+package derptest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/benpate/derp"
+)
+
+// UsePlugins replaces the global derp.Plugins with the provided reporters for the rest
+// of the test, and restores the original reporters when the test (and its subtests)
+// finish.  Tests that call UsePlugins must not run in parallel with each other.
+func UsePlugins(t testing.TB, reporters ...derp.Reporter) {
+
+	t.Helper()
+
+	original := derp.Plugins.Reporters()
+	t.Cleanup(func() { derp.SetPlugins(original...) })
+
+	derp.SetPlugins(reporters...)
+}
+
+// Record replaces the global derp.Plugins with a new RecordingReporter for the rest
+// of the test (see UsePlugins), and returns it so that the test can inspect every
+// error that was reported.
+func Record(t testing.TB) *RecordingReporter {
+
+	t.Helper()
+
+	result := NewRecordingReporter()
+	UsePlugins(t, result)
+
+	return result
+}
+
+// RecordContext returns a copy of the context that carries a new derp.Scope, along with
+// the RecordingReporter that receives every error reported through it (see derp.ReportContext).
+// Unlike Record, it leaves the global derp.Plugins alone, so it is safe for parallel tests.
+func RecordContext(ctx context.Context) (context.Context, *RecordingReporter) {
+
+	result := NewRecordingReporter()
+	return derp.ContextWithScope(ctx, derp.NewScope(result)), result
+}
@@ -0,0 +1,167 @@
+package derptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/benpate/derp"
+)
+
+// updateFlag is the test flag that rewrites golden files instead of comparing them.  derptest
+// does not register it (which would collide with packages that register their own), so
+// it only works in test binaries that define it.
+const updateFlag = "update"
+
+// updateVariable is the environment variable that rewrites golden files,
+// for test binaries that do not define an -update flag.
+const updateVariable = "DERPTEST_UPDATE"
+
+// normalizedKeys are the JSON keys whose values change from one run to the next.
+// NormalizedJSON replaces their values with placeholders, so that golden files are stable.
+var normalizedKeys = map[string]string{
+	"timestamp": "<timestamp>",
+	"id":        "<id>",
+	"errorId":   "<id>",
+	"duration":  "<duration>",
+	"stack":     "<stack>",
+}
+
+// NormalizedJSON serializes any error as indented JSON, with values that change from one run to
+// the next (timestamps, IDs, durations, and stacks) replaced by placeholders, at every level of
+// the wrap chain.  Object keys are sorted, so the output is stable enough for golden files.
+func NormalizedJSON(err error) ([]byte, error) {
+
+	encoded, marshalError := json.Marshal(err)
+
+	if marshalError != nil {
+		return nil, derp.Wrap(marshalError, "derptest.NormalizedJSON", "Unable to serialize error")
+	}
+
+	var value any
+
+	if unmarshalError := json.Unmarshal(encoded, &value); unmarshalError != nil {
+		return nil, derp.Wrap(unmarshalError, "derptest.NormalizedJSON", "Unable to parse serialized error")
+	}
+
+	// HTML escaping is disabled, so that placeholders like <timestamp> stay readable
+	var result bytes.Buffer
+	encoder := json.NewEncoder(&result)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+
+	if encodeError := encoder.Encode(normalize(value)); encodeError != nil {
+		return nil, derp.Wrap(encodeError, "derptest.NormalizedJSON", "Unable to serialize normalized error")
+	}
+
+	return result.Bytes(), nil
+}
+
+// AssertGolden compares the normalized JSON of an error (see NormalizedJSON) with the golden
+// file at testdata/<name>.golden.json.  When the test runs with -update (if the test binary
+// defines that flag) or with DERPTEST_UPDATE=1, the golden file is rewritten instead.  Like
+// the testify assertions, it reports a failure and returns false when the check fails.
+func AssertGolden(t testing.TB, name string, err error) bool {
+
+	t.Helper()
+
+	actual, normalizeError := NormalizedJSON(err)
+
+	if normalizeError != nil {
+		t.Errorf("unable to normalize error: %s", normalizeError)
+		return false
+	}
+
+	filename := filepath.Join("testdata", name+".golden.json")
+
+	if updateGolden() {
+
+		if writeError := writeGolden(filename, actual); writeError != nil {
+			t.Errorf("unable to update golden file: %s", writeError)
+			return false
+		}
+
+		return true
+	}
+
+	expected, readError := os.ReadFile(filename)
+
+	if readError != nil {
+		t.Errorf("unable to read golden file (run with %s=1 to create it): %s", updateVariable, readError)
+		return false
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("serialized error does not match %s (run with %s=1 to accept it)\n--- expected\n%s\n--- actual\n%s", filename, updateVariable, expected, actual)
+		return false
+	}
+
+	return true
+}
+
+// preservedKeys are the JSON keys whose values belong to the application (such as
+// Param("id", 123)), so NormalizedJSON never replaces anything inside of them.
+var preservedKeys = map[string]bool{
+	"fields": true,
+	"params": true,
+}
+
+// normalize replaces the values of normalizedKeys throughout a decoded JSON value.
+func normalize(value any) any {
+
+	switch typed := value.(type) {
+
+	case map[string]any:
+		for key, item := range typed {
+
+			if preservedKeys[key] {
+				continue
+			}
+
+			if placeholder, ok := normalizedKeys[key]; ok {
+				typed[key] = placeholder
+			} else {
+				typed[key] = normalize(item)
+			}
+		}
+
+	case []any:
+		for index, item := range typed {
+			typed[index] = normalize(item)
+		}
+	}
+
+	return value
+}
+
+// updateGolden returns TRUE if the test binary's -update flag is set, or if the
+// DERPTEST_UPDATE environment variable is set to a true value, such as "1".
+func updateGolden() bool {
+
+	if defined := flag.Lookup(updateFlag); defined != nil {
+		if value, _ := strconv.ParseBool(defined.Value.String()); value {
+			return true
+		}
+	}
+
+	value, _ := strconv.ParseBool(os.Getenv(updateVariable))
+	return value
+}
+
+// writeGolden writes a golden file, creating its directory if necessary.
+func writeGolden(filename string, content []byte) error {
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return derp.Wrap(err, "derptest.writeGolden", "Unable to create golden file directory", filename)
+	}
+
+	if err := os.WriteFile(filename, content, 0o644); err != nil {
+		return derp.Wrap(err, "derptest.writeGolden", "Unable to write golden file", filename)
+	}
+
+	return nil
+}
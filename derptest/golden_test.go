@@ -0,0 +1,121 @@
+package derptest
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benpate/derp"
+)
+
+// update is defined here, just as packages that use derptest define it, so that "go test -update"
+// rewrites golden files.  derptest itself never registers it, so there is no "flag redefined" panic.
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestGolden_Error(t *testing.T) {
+
+	err := derp.NotFound("users.Load", "user {id} not found",
+		derp.Param("id", 123),
+		derp.WithID(derp.NewID()),
+		derp.WithTags("db"),
+		"detail",
+	)
+
+	AssertGolden(t, "error", err)
+}
+
+func TestGolden_HTTPError(t *testing.T) {
+
+	request := httptest.NewRequest(http.MethodPost, "https://example.com/users?token=secret", strings.NewReader(`{"name":"Alice"}`))
+	request.Header.Set("Authorization", "Bearer secret")
+
+	response := &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Status:     "502 Bad Gateway",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       http.NoBody,
+		Request:    request,
+	}
+
+	err := derp.NewHTTPError(request, response)
+	err.Duration = 1500 * time.Millisecond
+
+	AssertGolden(t, "http_error", err)
+}
+
+func TestGolden_Chain(t *testing.T) {
+
+	inner := derp.Internal("db.Query", "connection refused", derp.WithWrappedValue(errors.New("dial tcp: refused")))
+	middle := derp.Wrap(inner, "users.Load", "Unable to load user", derp.WithField("userId", 123))
+	outer := derp.Wrap(middle, "handlers.GetUser", "Unable to get user")
+
+	AssertGolden(t, "chain", outer)
+}
+
+func TestGolden_Mismatch(t *testing.T) {
+
+	if updateGolden() {
+		t.Skip("golden files are being rewritten")
+	}
+
+	fake := &fakeT{}
+	check(t, fake, AssertGolden(fake, "error", derp.Internal("other", "message")), false)
+
+	fake = &fakeT{}
+	check(t, fake, AssertGolden(fake, "missing", derp.Internal("other", "message")), false)
+}
+
+func TestUpdateGolden(t *testing.T) {
+
+	previous := *update
+	t.Cleanup(func() { *update = previous })
+
+	// The -update flag wins...
+	*update = true
+	t.Setenv(updateVariable, "")
+
+	if !updateGolden() {
+		t.Error("expected -update to rewrite golden files")
+	}
+
+	// ...and the environment variable is used otherwise
+	*update = false
+
+	for value, expected := range map[string]bool{"": false, "0": false, "false": false, "1": true, "true": true} {
+		t.Setenv(updateVariable, value)
+
+		if actual := updateGolden(); actual != expected {
+			t.Errorf("%s=%q: expected %t, got %t", updateVariable, value, expected, actual)
+		}
+	}
+}
+
+func TestNormalizedJSON(t *testing.T) {
+
+	first, err := NormalizedJSON(derp.NotFound("location", "message", derp.WithID(derp.NewID()), derp.Param("id", 1)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	second, err := NormalizedJSON(derp.NotFound("location", "message", derp.WithID(derp.NewID()), derp.Param("id", 1)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(first) != string(second) {
+		t.Errorf("expected identical output:\n%s\n%s", first, second)
+	}
+
+	// Application values are never normalized
+	if !strings.Contains(string(first), `"id": 1`) {
+		t.Errorf("expected params to be preserved:\n%s", first)
+	}
+}
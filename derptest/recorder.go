@@ -0,0 +1,122 @@
+package derptest
+
+import (
+	"sync"
+	"time"
+)
+
+// RecordingReporter is a derp.Reporter that remembers every error it receives,
+// so that tests can check what was reported.  It is safe for concurrent use.
+type RecordingReporter struct {
+	lock    sync.Mutex
+	errors  []error
+	changed chan struct{} // closed (and replaced) whenever an error is recorded
+}
+
+// NewRecordingReporter returns an empty RecordingReporter.
+func NewRecordingReporter() *RecordingReporter {
+	return &RecordingReporter{
+		changed: make(chan struct{}),
+	}
+}
+
+// Report implements the derp.Reporter interface.
+func (reporter *RecordingReporter) Report(err error) {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	reporter.errors = append(reporter.errors, err)
+
+	// Wake every goroutine in WaitFor
+	if reporter.changed != nil {
+		close(reporter.changed)
+	}
+
+	reporter.changed = make(chan struct{})
+}
+
+// Errors returns a copy of every error recorded so far, in the order they were reported.
+func (reporter *RecordingReporter) Errors() []error {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	result := make([]error, len(reporter.errors))
+	copy(result, reporter.errors)
+
+	return result
+}
+
+// Len returns the number of errors recorded so far.
+func (reporter *RecordingReporter) Len() int {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	return len(reporter.errors)
+}
+
+// Last returns the most recently recorded error, or nil if nothing has been recorded.
+func (reporter *RecordingReporter) Last() error {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	if len(reporter.errors) == 0 {
+		return nil
+	}
+
+	return reporter.errors[len(reporter.errors)-1]
+}
+
+// Reset forgets every error recorded so far.
+func (reporter *RecordingReporter) Reset() {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	reporter.errors = nil
+}
+
+// WaitFor waits until an error that matches the provided function has been recorded,
+// and returns it.  Errors that were recorded before WaitFor was called also count.  A nil
+// match function matches any error.  If no error matches before the timeout, WaitFor
+// returns nil.  This is useful for errors reported by background goroutines.
+func (reporter *RecordingReporter) WaitFor(timeout time.Duration, match func(error) bool) error {
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	checked := 0
+
+	for {
+
+		reporter.lock.Lock()
+
+		// Start over if the reporter was Reset while waiting
+		if checked > len(reporter.errors) {
+			checked = 0
+		}
+
+		for ; checked < len(reporter.errors); checked++ {
+			if err := reporter.errors[checked]; match == nil || match(err) {
+				reporter.lock.Unlock()
+				return err
+			}
+		}
+
+		if reporter.changed == nil {
+			reporter.changed = make(chan struct{})
+		}
+
+		changed := reporter.changed
+		reporter.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return nil
+		}
+	}
+}
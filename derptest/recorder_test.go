@@ -0,0 +1,120 @@
+package derptest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benpate/derp"
+)
+
+func TestRecordingReporter(t *testing.T) {
+
+	reporter := NewRecordingReporter()
+
+	if reporter.Last() != nil || reporter.Len() != 0 {
+		t.Fatal("expected an empty reporter")
+	}
+
+	first := derp.NotFound("first", "message")
+	second := derp.Internal("second", "message")
+
+	reporter.Report(first)
+	reporter.Report(second)
+
+	if errs := reporter.Errors(); len(errs) != 2 || derp.Location(errs[0]) != "first" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	if derp.Location(reporter.Last()) != "second" {
+		t.Errorf("unexpected last error: %v", reporter.Last())
+	}
+
+	reporter.Reset()
+
+	if reporter.Len() != 0 {
+		t.Error("expected Reset to forget every error")
+	}
+}
+
+func TestRecordingReporter_Concurrent(t *testing.T) {
+
+	reporter := NewRecordingReporter()
+
+	var wait sync.WaitGroup
+
+	for index := 0; index < 50; index++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			reporter.Report(derp.Internal("location", "message"))
+		}()
+	}
+
+	wait.Wait()
+
+	if reporter.Len() != 50 {
+		t.Errorf("expected 50 errors, got %d", reporter.Len())
+	}
+}
+
+func TestRecordingReporter_WaitFor(t *testing.T) {
+
+	reporter := NewRecordingReporter()
+	reporter.Report(derp.Internal("early", "message"))
+
+	// Errors recorded before the call count
+	if err := reporter.WaitFor(time.Second, nil); derp.Location(err) != "early" {
+		t.Errorf("expected the early error, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		reporter.Report(derp.Internal("other", "message"))
+		reporter.Report(derp.NotFound("background", "message"))
+	}()
+
+	err := reporter.WaitFor(time.Second, derp.IsNotFound)
+
+	if derp.Location(err) != "background" {
+		t.Errorf("expected the background error, got %v", err)
+	}
+
+	// Timeouts return nil
+	if err := reporter.WaitFor(10*time.Millisecond, derp.IsBadRequest); err != nil {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
+
+func TestRecord(t *testing.T) {
+
+	original := derp.Plugins.Reporters()
+
+	t.Run("recording", func(t *testing.T) {
+
+		reporter := Record(t)
+		derp.Report(derp.NotFound("location", "message"))
+
+		if reporter.Len() != 1 {
+			t.Errorf("expected one recorded error, got %d", reporter.Len())
+		}
+	})
+
+	// The original reporters are restored when the subtest finishes
+	if restored := derp.Plugins.Reporters(); len(restored) != len(original) {
+		t.Errorf("expected %d reporters to be restored, got %d", len(original), len(restored))
+	}
+}
+
+func TestRecordContext(t *testing.T) {
+
+	t.Parallel()
+
+	ctx, reporter := RecordContext(context.Background())
+	derp.ReportContext(ctx, derp.NotFound("location", "message"))
+
+	if reporter.Len() != 1 {
+		t.Errorf("expected one recorded error, got %d", reporter.Len())
+	}
+}
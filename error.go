@@ -1,21 +1,42 @@
 package derp
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Error represents a runtime error, including a numeric code, the location
 // where it occurred, a human-readable message, and an optional wrapped error.
 type Error struct {
-	Code         int    `json:"code"`                 // Numeric error code (such as an HTTP status code) to report to the client.
-	Location     string `json:"location"`             // Function name (or other location description) of where the error occurred
-	Message      string `json:"message"`              // Primary (top-level) error message for this error
-	URL          string `json:"url,omitempty"`        // URL to a web page with more information about this error
-	Details      []any  `json:"details,omitempty"`    // Additional information related to this error message, such as parameters to the function that caused the error.
-	TimeStamp    int64  `json:"timestamp"`            // Unix Epoch timestamp of the date/time when this error was created
-	WrappedValue error  `json:"innerError,omitempty"` // An underlying error object used to identify the root cause of this error.
+	ID           string    `json:"id,omitempty"`         // Unique ID that customers can quote to support.  Wrapped errors share the ID of their root cause.
+	Code         int       `json:"code"`                 // Numeric error code (such as an HTTP status code) to report to the client.
+	Location     string    `json:"location"`             // Function name (or other location description) of where the error occurred
+	Message      string    `json:"message"`              // Primary (top-level) error message for this error
+	URL          string    `json:"url,omitempty"`        // URL to a web page with more information about this error
+	Details      []any     `json:"details,omitempty"`    // Additional information related to this error message, such as parameters to the function that caused the error.
+	TimeStamp    time.Time `json:"timestamp"`            // Date/time when this error was created.  Written to JSON in the format set by SetTimeStampFormat.
+	WrappedValue error     `json:"innerError,omitempty"` // An underlying error object used to identify the root cause of this error.
+
+	Retryable  *bool         `json:"retryable,omitempty"`  // Explicit decision on whether to retry the failed operation.  If nil, the decision is derived from Code.
+	RetryAfter time.Duration `json:"retryAfter,omitempty"` // Recommended delay before retrying the failed operation.  If zero, the WrappedValue is consulted.
+	Severity   Severity      `json:"severity,omitempty"`   // How bad this error is.  If unset, it is derived from the wrapped errors and from Code.
+
+	Fields map[string]any `json:"fields,omitempty"` // Typed key/value pairs (such as "userId") that reporters can index.  Merged with the fields of wrapped errors.
+	Tags   []string       `json:"tags,omitempty"`   // Short labels (such as "billing") that reporters can filter and route on.  Merged with the tags of wrapped errors.
+
+	Template string         `json:"template,omitempty"` // Message before its placeholders were filled by Param.  Stable across occurrences, so it is used for grouping.
+	Params   map[string]any `json:"params,omitempty"`   // Values that fill the placeholders of Template.
+
+	PublicMessage string `json:"publicMessage,omitempty"` // Text that is safe to show end users, unlike Message.  Used when MessageKey is not found in a Catalog.
+	MessageKey    string `json:"messageKey,omitempty"`    // Catalog key of a localized, user-facing message.
 }
 
 // IsZero returns true if this Error is empty / uninitialized
 func (err Error) IsZero() bool {
+	if err.ID != "" {
+		return false
+	}
+
 	if err.Code != 0 {
 		return false
 	}
@@ -40,6 +61,42 @@ func (err Error) IsZero() bool {
 		return false
 	}
 
+	if err.Retryable != nil {
+		return false
+	}
+
+	if err.RetryAfter != 0 {
+		return false
+	}
+
+	if err.Severity != SeverityUnset {
+		return false
+	}
+
+	if len(err.Fields) > 0 {
+		return false
+	}
+
+	if len(err.Tags) > 0 {
+		return false
+	}
+
+	if err.Template != "" {
+		return false
+	}
+
+	if len(err.Params) > 0 {
+		return false
+	}
+
+	if err.PublicMessage != "" {
+		return false
+	}
+
+	if err.MessageKey != "" {
+		return false
+	}
+
 	return true
 }
 
@@ -54,6 +111,28 @@ func (err Error) GetErrorCode() int {
 	return err.Code
 }
 
+// GetHTTPStatus returns the HTTP status that describes this Error to clients (see HTTPStatus),
+// so that reporters can classify registered application codes without importing derp.
+func (err Error) GetHTTPStatus() int {
+	return HTTPStatus(err)
+}
+
+// GetErrorID returns the unique ID of the root cause of this Error.  If the wrapped
+// errors have no ID, it returns the ID of this Error, which may be empty.
+func (err Error) GetErrorID() string {
+
+	if id := ErrorID(err.WrappedValue); id != "" {
+		return id
+	}
+
+	return err.ID
+}
+
+// GetFingerprint returns a short, stable identifier that groups similar errors together (see Fingerprint).
+func (err Error) GetFingerprint() string {
+	return Fingerprint(err)
+}
+
 // GetLocation returns the error Location embedded in this Error.
 func (err Error) GetLocation() string {
 	return err.Location
@@ -64,14 +143,114 @@ func (err Error) GetMessage() string {
 	return err.Message
 }
 
-// GetRetryAfter returns the retry-after duration (in seconds)
-// provided by the WrappedValue.  If the WrappedValue is nil,
-// or does not implement the RetryAfterGetter interface,
-// this method returns 0
+// GetTemplate returns the message template of this Error, or the Message
+// itself if it was not created from a template.
+func (err Error) GetTemplate() string {
+
+	if err.Template != "" {
+		return err.Template
+	}
+
+	return err.Message
+}
+
+// GetPublicMessage returns the user-facing message of this Error.  If none was set,
+// it returns the public message of the wrapped error, if any.
+func (err Error) GetPublicMessage() string {
+
+	if err.PublicMessage != "" {
+		return err.PublicMessage
+	}
+
+	if getter, ok := AsType[PublicMessageGetter](err.WrappedValue); ok {
+		return getter.GetPublicMessage()
+	}
+
+	return ""
+}
+
+// GetMessageKey returns the catalog key of this Error.  If none was set,
+// it returns the catalog key of the wrapped error, if any.
+func (err Error) GetMessageKey() string {
+
+	if err.MessageKey != "" {
+		return err.MessageKey
+	}
+
+	return MessageKey(err.WrappedValue)
+}
+
+// GetRetryAfter returns the retry-after duration set by WithRetryAfter.
+// If none was set, it returns the duration provided by the WrappedValue.
+// If the WrappedValue is nil, or does not implement the RetryAfterGetter
+// interface, this method returns 0
 func (err Error) GetRetryAfter() time.Duration {
+
+	if err.RetryAfter > 0 {
+		return err.RetryAfter
+	}
+
 	return RetryAfter(err.WrappedValue)
 }
 
+// GetRetryable returns TRUE if the operation that caused this error is worth retrying.
+// An explicit decision made with WithRetryable always wins.  Otherwise, the decision is
+// delegated to the wrapped error, or derived from this error's Code.
+func (err Error) GetRetryable() bool {
+
+	if err.Retryable != nil {
+		return *err.Retryable
+	}
+
+	if getter, ok := AsType[RetryableGetter](err.WrappedValue); ok {
+		return getter.GetRetryable()
+	}
+
+	return isRetryableDefault(err)
+}
+
+// GetSeverity returns the severity set by WithSeverity.  If none was set, it returns the
+// highest severity set anywhere in the wrapped chain, or else a default derived from Code.
+func (err Error) GetSeverity() Severity {
+
+	if err.Severity != SeverityUnset {
+		return err.Severity
+	}
+
+	if severity := explicitSeverity(err.WrappedValue); severity != SeverityUnset {
+		return severity
+	}
+
+	return codeSeverity(err.Code)
+}
+
+// GetFields returns a new map that merges the Fields of this Error with the fields
+// of every error that it wraps.  Outer fields override inner fields with the same key.
+func (err Error) GetFields() map[string]any {
+
+	result := AllFields(err.WrappedValue)
+
+	if len(err.Fields) == 0 {
+		return result
+	}
+
+	if result == nil {
+		result = make(map[string]any, len(err.Fields))
+	}
+
+	for key, value := range err.Fields {
+		result[key] = value
+	}
+
+	return result
+}
+
+// GetTags returns a new slice that merges the Tags of this Error with the tags of
+// every error that it wraps.  Outer tags come first, and each tag appears only once.
+func (err Error) GetTags() []string {
+	return appendTags(appendTags(nil, err.Tags...), AllTags(err.WrappedValue)...)
+}
+
 // GetURL returns the help URL embedded in this Error.
 func (err Error) GetURL() string {
 	return err.URL
@@ -83,10 +262,66 @@ func (err Error) GetDetails() []any {
 }
 
 // GetTimeStamp returns the error TimeStamp embedded in this Error.
-func (err Error) GetTimeStamp() int64 {
+func (err Error) GetTimeStamp() time.Time {
 	return err.TimeStamp
 }
 
+// MarshalJSON implements the json.Marshaler interface.  It applies the current
+// Redactor, so that sensitive values never reach serialized output.
+func (err Error) MarshalJSON() ([]byte, error) {
+
+	// errorJSON has no methods, so that encoding it does not recurse into MarshalJSON.
+	// Wrapped errors are redacted by their own MarshalJSON methods.
+	type errorJSON Error
+
+	// Derived values are written out, so that consumers do not need derp's rules to read them.
+	err.ID = err.GetErrorID()
+	err.Severity = err.GetSeverity()
+	err.Fields = err.GetFields()
+	err.Tags = err.GetTags()
+
+	if redactor := currentRedactor(); redactor != nil {
+		err = redactor.redactError(err, false)
+	}
+
+	// Timestamps are shadowed by a field that is encoded in the configured format.
+	return json.Marshal(struct {
+		errorJSON
+		TimeStamp any `json:"timestamp"`
+	}{
+		errorJSON: errorJSON(err),
+		TimeStamp: encodeTimeStamp(err.TimeStamp),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.  Timestamps are read
+// in any of the formats that MarshalJSON writes (see decodeTimeStamp).
+func (err *Error) UnmarshalJSON(data []byte) error {
+
+	// errorJSON has no methods, so that decoding it does not recurse into UnmarshalJSON.
+	type errorJSON Error
+
+	value := struct {
+		*errorJSON
+		TimeStamp json.RawMessage `json:"timestamp"`
+	}{
+		errorJSON: (*errorJSON)(err),
+	}
+
+	if unmarshalError := json.Unmarshal(data, &value); unmarshalError != nil {
+		return unmarshalError
+	}
+
+	timestamp, decodeError := decodeTimeStamp(value.TimeStamp)
+
+	if decodeError != nil {
+		return decodeError
+	}
+
+	err.TimeStamp = timestamp
+	return nil
+}
+
 // Unwrap supports Go 1.13+ error unwrapping
 func (err Error) Unwrap() error {
 	return err.WrappedValue
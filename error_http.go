@@ -1,6 +1,7 @@
 package derp
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,14 +12,24 @@ type HTTPError struct {
 	Request  HTTPRequestReport  `json:"request"`  // Details of the HTTP request that failed
 	Response HTTPResponseReport `json:"response"` // Details of the HTTP response that was returned
 
+	Duration time.Duration `json:"duration,omitempty"` // Time spent waiting for the response, when measured by derp.Transport
+	Attempt  int           `json:"attempt,omitempty"`  // Number of the attempt that failed, when the request was retried
+
 	WrappedValue error `json:"innerError,omitempty"` // An underlying error object used to identify the root cause of this error.
 }
 
-// NewHTTPError creates a new HTTPError object from the given request and response
-func NewHTTPError(request *http.Request, response *http.Response) HTTPError {
+// NewHTTPError creates a new HTTPError object from the given request and response.
+// Options such as WithResponseBody also capture the bodies of the transaction.
+func NewHTTPError(request *http.Request, response *http.Response, options ...HTTPOption) HTTPError {
 
 	result := HTTPError{}
 
+	capture := httpCapture{}
+
+	for _, option := range options {
+		option(&capture)
+	}
+
 	if request != nil {
 
 		result.Request = HTTPRequestReport{
@@ -30,6 +41,8 @@ func NewHTTPError(request *http.Request, response *http.Response) HTTPError {
 		if request.URL != nil {
 			result.Request.URL = request.URL.String()
 		}
+
+		result.Request.Body = captureRequestBody(request, capture.requestBodyLimit)
 	}
 
 	if response != nil {
@@ -37,6 +50,7 @@ func NewHTTPError(request *http.Request, response *http.Response) HTTPError {
 			StatusCode: response.StatusCode,
 			Status:     response.Status,
 			Header:     response.Header,
+			Body:       captureResponseBody(response, capture.responseBodyLimit),
 		}
 	}
 
@@ -45,9 +59,9 @@ func NewHTTPError(request *http.Request, response *http.Response) HTTPError {
 
 // WrapHTTPError creates a new HTTPError object from the given request/response
 // ands wraps an existing error
-func WrapHTTPError(err error, request *http.Request, response *http.Response) HTTPError {
+func WrapHTTPError(err error, request *http.Request, response *http.Response, options ...HTTPOption) HTTPError {
 
-	result := NewHTTPError(request, response)
+	result := NewHTTPError(request, response, options...)
 	result.WrappedValue = err
 
 	return result
@@ -55,29 +69,58 @@ func WrapHTTPError(err error, request *http.Request, response *http.Response) HT
 
 // HTTPRequestReport includes details of a failed HTTP request
 type HTTPRequestReport struct {
-	URL    string      `json:"url"`    // Fully qualified URL that was requested
-	Method string      `json:"method"` // HTTP method (GET, POST, etc) used to make the request
-	Header http.Header `json:"header"` // Headers sent with the request.  NOTE: these are shared with the original http.Request, and may include credentials.
+	URL    string      `json:"url"`            // Fully qualified URL that was requested
+	Method string      `json:"method"`         // HTTP method (GET, POST, etc) used to make the request
+	Header http.Header `json:"header"`         // Headers sent with the request.  NOTE: these are shared with the original http.Request, and may include credentials, which are removed by the Redactor before serialization.
+	Body   string      `json:"body,omitempty"` // Bounded prefix of the request body, captured only when requested by WithRequestBody.
 }
 
 // HTTPResponseReport includes response details of a failed HTTP request
 type HTTPResponseReport struct {
-	StatusCode int         `json:"statusCode"` // Numeric HTTP status code returned by the server
-	Status     string      `json:"status"`     // Human-readable status line returned by the server
-	Header     http.Header `json:"header"`     // Headers returned with the response.  NOTE: these are shared with the original http.Response.
+	StatusCode int         `json:"statusCode"`     // Numeric HTTP status code returned by the server
+	Status     string      `json:"status"`         // Human-readable status line returned by the server
+	Header     http.Header `json:"header"`         // Headers returned with the response.  NOTE: these are shared with the original http.Response.
+	Body       string      `json:"body,omitempty"` // Bounded prefix of the response body, captured only when requested by WithResponseBody.
 }
 
 // Error implements the Error interface, which allows derp.Error objects to be
 // used anywhere a standard error is used.
 func (err HTTPError) Error() string {
+
+	// Transport failures have no response status, so describe the underlying failure instead.
+	if err.Response.Status == "" && NotNil(err.WrappedValue) {
+		return err.WrappedValue.Error()
+	}
+
 	return err.Response.Status
 }
 
-// GetErrorCode returns the error Code embedded in this Error.
+// GetErrorCode returns the HTTP status code of the response.  When no response
+// was received (such as a transport failure), the code of the wrapped error is used.
 func (err HTTPError) GetErrorCode() int {
+
+	if err.Response.StatusCode == 0 && NotNil(err.WrappedValue) {
+		return ErrorCode(err.WrappedValue)
+	}
+
 	return err.Response.StatusCode
 }
 
+// MarshalJSON implements the json.Marshaler interface.  It applies the current
+// Redactor, so that credentials in request and response headers are never serialized.
+func (err HTTPError) MarshalJSON() ([]byte, error) {
+
+	// httpErrorJSON has no methods, so that encoding it does not recurse into MarshalJSON.
+	// Wrapped errors are redacted by their own MarshalJSON methods.
+	type httpErrorJSON HTTPError
+
+	if redactor := currentRedactor(); redactor != nil {
+		err = redactor.redactHTTPError(err, false)
+	}
+
+	return json.Marshal(httpErrorJSON(err))
+}
+
 // Unwrap returns the inner error wrapped by this HTTPError.
 func (err HTTPError) Unwrap() error {
 	return err.WrappedValue
@@ -96,6 +139,18 @@ func (err HTTPError) Unwrap() error {
 // https://developer.mozilla.org/en-US/docs/Web/HTTP/Reference/Status/429
 func (err HTTPError) GetRetryAfter() time.Duration {
 
+	if retryAfter, ok := err.retryAfter(); ok {
+		return retryAfter
+	}
+
+	// If no value is found, wait 1 hour before retrying
+	return time.Hour
+}
+
+// retryAfter parses the retry-after headers of the response, and returns
+// FALSE if none of them contains a recognizable value.
+func (err HTTPError) retryAfter() (time.Duration, bool) {
+
 	// List of headers that might contain retry-after information
 	headers := []string{
 		"Retry-After",        // https://developer.mozilla.org/en-US/docs/Web/HTTP/Reference/Headers/Retry-After
@@ -117,29 +172,28 @@ func (err HTTPError) GetRetryAfter() time.Duration {
 		// Integers represent the number of seconds to wait
 		// (named `atoiError` so that it does not shadow the `err` receiver)
 		if seconds, atoiError := strconv.Atoi(value); atoiError == nil {
-			return nonNegative(time.Duration(seconds) * time.Second)
+			return nonNegative(time.Duration(seconds) * time.Second), true
 		}
 
 		// RFC3339 timestamps represent the time when the rate limit resets
 		if resetAt, parseError := time.Parse(time.RFC3339, value); parseError == nil {
-			return nonNegative(time.Until(resetAt))
+			return nonNegative(time.Until(resetAt)), true
 		}
 
 		// RFC1123 timestamps represent the time when the rate limit resets
 		if resetAt, parseError := time.Parse(time.RFC1123, value); parseError == nil {
-			return nonNegative(time.Until(resetAt))
+			return nonNegative(time.Until(resetAt)), true
 		}
 
 		// Last resort: the remaining legal HTTP-date formats (RFC850 and asctime), which
 		// RFC9110 requires recipients to accept.
 		// https://www.rfc-editor.org/rfc/rfc9110.html#name-date-time-formats
 		if resetAt, parseError := http.ParseTime(value); parseError == nil {
-			return nonNegative(time.Until(resetAt))
+			return nonNegative(time.Until(resetAt)), true
 		}
 	}
 
-	// If no value is found, wait 1 hour before retrying
-	return time.Hour
+	return 0, false
 }
 
 // nonNegative clamps a retry-after duration to zero.
@@ -0,0 +1,187 @@
+package derp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// truncationMarker is appended to captured bodies that were longer than the capture limit.
+const truncationMarker = "...[truncated]"
+
+// HTTPOption defines a function that changes how NewHTTPError and WrapHTTPError
+// capture the details of an HTTP transaction.
+type HTTPOption func(*httpCapture)
+
+// httpCapture configures which parts of an HTTP transaction are captured into an HTTPError.
+type httpCapture struct {
+	requestBodyLimit  int // Maximum number of request body bytes to capture.  Zero captures nothing.
+	responseBodyLimit int // Maximum number of response body bytes to capture.  Zero captures nothing.
+}
+
+// WithRequestBody returns an option that captures up to `limit` bytes of the request body.
+// The request body is restored afterwards, so that it can still be read by the caller.
+func WithRequestBody(limit int) HTTPOption {
+	return func(capture *httpCapture) {
+		capture.requestBodyLimit = limit
+	}
+}
+
+// WithResponseBody returns an option that captures up to `limit` bytes of the response body,
+// such as the explanation that an upstream API returns with a 400 Bad Request.
+// The response body is restored afterwards, so that it can still be read by the caller.
+func WithResponseBody(limit int) HTTPOption {
+	return func(capture *httpCapture) {
+		capture.responseBodyLimit = limit
+	}
+}
+
+// WithBodies returns an option that captures up to `limit` bytes of
+// both the request and the response bodies.
+func WithBodies(limit int) HTTPOption {
+	return func(capture *httpCapture) {
+		capture.requestBodyLimit = limit
+		capture.responseBodyLimit = limit
+	}
+}
+
+// captureRequestBody reads a bounded prefix of the request body without consuming it.
+func captureRequestBody(request *http.Request, limit int) string {
+
+	if limit <= 0 {
+		return ""
+	}
+
+	// Prefer GetBody, which returns a fresh copy of the body even after the
+	// request has been sent (and its original Body consumed).
+	if request.GetBody != nil {
+		if body, err := request.GetBody(); err == nil {
+			defer body.Close()
+			prefix, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+			return formatBody(prefix, limit, request.Header.Get("Content-Type"))
+		}
+	}
+
+	if request.Body == nil || request.Body == http.NoBody {
+		return ""
+	}
+
+	var prefix []byte
+	prefix, request.Body = peekBody(request.Body, limit)
+	return formatBody(prefix, limit, request.Header.Get("Content-Type"))
+}
+
+// captureResponseBody reads a bounded prefix of the response body without consuming it.
+func captureResponseBody(response *http.Response, limit int) string {
+
+	if limit <= 0 || response.Body == nil || response.Body == http.NoBody {
+		return ""
+	}
+
+	var prefix []byte
+	prefix, response.Body = peekBody(response.Body, limit)
+	return formatBody(prefix, limit, response.Header.Get("Content-Type"))
+}
+
+// peekBody reads up to limit+1 bytes from the body (the extra byte detects truncation),
+// and returns them along with a replacement body that yields the complete original content.
+func peekBody(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+
+	// Read errors are swallowed: whatever was read is still returned to the
+	// caller through the replacement body, followed by the same error.
+	prefix, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+
+	return prefix, restoredBody{
+		Reader: io.MultiReader(bytes.NewReader(prefix), body),
+		Closer: body,
+	}
+}
+
+// restoredBody replays a captured prefix before the remainder of the
+// original body, and closes the original body when it is closed.
+type restoredBody struct {
+	io.Reader
+	io.Closer
+}
+
+// formatBody converts a captured prefix into a readable string.  Complete JSON bodies are
+// indented, binary bodies are summarized, and truncated bodies end with a truncation marker.
+func formatBody(prefix []byte, limit int, contentType string) string {
+
+	if len(prefix) == 0 {
+		return ""
+	}
+
+	truncated := len(prefix) > limit
+
+	if truncated {
+		prefix = trimPartialRune(prefix[:limit])
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	// Binary content is not useful in an error report, so only describe it.
+	if !isTextMediaType(mediaType) && !utf8.Valid(prefix) {
+
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+
+		return "[binary content: " + mediaType + "]"
+	}
+
+	// RULE: Only complete JSON documents can be indented; a truncated one is invalid JSON.
+	if !truncated && isJSONMediaType(mediaType) {
+		var buffer bytes.Buffer
+		if err := json.Indent(&buffer, prefix, "", "\t"); err == nil {
+			return buffer.String()
+		}
+	}
+
+	if truncated {
+		return string(prefix) + truncationMarker
+	}
+
+	return string(prefix)
+}
+
+// trimPartialRune removes an incomplete multi-byte character from the end of a
+// truncated body, so that a cut in the middle of a character does not make
+// readable text look like binary content.
+func trimPartialRune(value []byte) []byte {
+
+	for index := len(value) - 1; index >= 0 && index >= len(value)-utf8.UTFMax; index-- {
+		if utf8.RuneStart(value[index]) {
+			if !utf8.FullRune(value[index:]) {
+				return value[:index]
+			}
+			break
+		}
+	}
+
+	return value
+}
+
+// isJSONMediaType returns TRUE for application/json and any +json structured syntax suffix.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isTextMediaType returns TRUE for media types that are known to contain readable text.
+func isTextMediaType(mediaType string) bool {
+
+	if strings.HasPrefix(mediaType, "text/") || isJSONMediaType(mediaType) {
+		return true
+	}
+
+	switch mediaType {
+	case "application/xml", "application/x-www-form-urlencoded", "application/javascript":
+		return true
+	}
+
+	return strings.HasSuffix(mediaType, "+xml")
+}
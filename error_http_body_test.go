@@ -0,0 +1,133 @@
+package derp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPBody_ResponseCaptured(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 400,
+		Status:     "400 Bad Request",
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader("missing required field: name")),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(1024))
+	require.Equal(t, "missing required field: name", httpError.Response.Body)
+
+	// The body is restored, so the caller can still read all of it
+	remaining, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "missing required field: name", string(remaining))
+}
+
+func TestHTTPBody_NotCapturedByDefault(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader("explanation")),
+	}
+
+	httpError := NewHTTPError(nil, response)
+	require.Equal(t, "", httpError.Response.Body)
+}
+
+func TestHTTPBody_Truncated(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader("0123456789")),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(4))
+	require.Equal(t, "0123"+truncationMarker, httpError.Response.Body)
+
+	// The complete body is still available to the caller
+	remaining, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(remaining))
+}
+
+func TestHTTPBody_TruncatedMultiByte(t *testing.T) {
+
+	// "é" is two bytes, so a four-byte limit cuts it in half
+	response := &http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader("abcé and more")),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(4))
+	require.Equal(t, "abc"+truncationMarker, httpError.Response.Body)
+}
+
+func TestHTTPBody_JSON(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 422,
+		Header:     http.Header{"Content-Type": []string{"application/problem+json; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":"invalid","field":"name"}`)),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(1024))
+	require.Equal(t, "{\n\t\"error\": \"invalid\",\n\t\"field\": \"name\"\n}", httpError.Response.Body)
+}
+
+func TestHTTPBody_Binary(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 500,
+		Header:     http.Header{"Content-Type": []string{"image/png"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0xfe})),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(1024))
+	require.Equal(t, "[binary content: image/png]", httpError.Response.Body)
+}
+
+func TestHTTPBody_RequestGetBody(t *testing.T) {
+
+	// http.NewRequest populates GetBody, so the body is captured without being consumed
+	request, err := http.NewRequest(http.MethodPost, "https://example.com", strings.NewReader("name=john"))
+	require.NoError(t, err)
+
+	httpError := NewHTTPError(request, nil, WithRequestBody(1024))
+	require.Equal(t, "name=john", httpError.Request.Body)
+
+	remaining, err := io.ReadAll(request.Body)
+	require.NoError(t, err)
+	require.Equal(t, "name=john", string(remaining))
+}
+
+func TestHTTPBody_RequestBody(t *testing.T) {
+
+	// Without GetBody, the body is read and then restored
+	request := &http.Request{
+		Method: http.MethodPost,
+		Body:   io.NopCloser(strings.NewReader("name=john")),
+	}
+
+	httpError := WrapHTTPError(nil, request, nil, WithBodies(1024))
+	require.Equal(t, "name=john", httpError.Request.Body)
+
+	remaining, err := io.ReadAll(request.Body)
+	require.NoError(t, err)
+	require.Equal(t, "name=john", string(remaining))
+}
+
+func TestHTTPBody_Redacted(t *testing.T) {
+
+	response := &http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader("unknown account user@example.com")),
+	}
+
+	httpError := NewHTTPError(nil, response, WithResponseBody(1024))
+	require.NotContains(t, Serialize(httpError), "user@example.com")
+}
@@ -3,6 +3,7 @@ package derp
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -73,7 +74,7 @@ func TestErrorAccessors(t *testing.T) {
 		Message:   "the message",
 		URL:       "https://example.com/help",
 		Details:   []any{"a", "b"},
-		TimeStamp: 1234567890,
+		TimeStamp: time.Unix(1234567890, 0),
 	}
 
 	require.Equal(t, 404, err.GetErrorCode())
@@ -81,5 +82,5 @@ func TestErrorAccessors(t *testing.T) {
 	require.Equal(t, "the message", err.GetMessage())
 	require.Equal(t, "https://example.com/help", err.GetURL())
 	require.Equal(t, []any{"a", "b"}, err.GetDetails())
-	require.Equal(t, int64(1234567890), err.GetTimeStamp())
+	require.Equal(t, time.Unix(1234567890, 0), err.GetTimeStamp())
 }
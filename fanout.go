@@ -0,0 +1,315 @@
+package derp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// errReporterPanicked, errReporterTimedOut, and errReporterOverloaded are wrapped
+// by the errors that describe a failed delivery to a reporter.
+var (
+	errReporterPanicked   = errors.New("reporter panicked")
+	errReporterTimedOut   = errors.New("reporter timed out")
+	errReporterOverloaded = errors.New("reporter overloaded")
+)
+
+// fallback is the Reporter that receives descriptions of failed deliveries.
+// A nil value means that they are written to os.Stderr.
+var fallback atomic.Pointer[Reporter]
+
+// SetFallbackReporter sets the Reporter that is told when another reporter panics or
+// times out.  It should be simple and dependable, because its own failures are ignored.
+// Passing nil restores the default, which writes to os.Stderr.
+func SetFallbackReporter(reporter Reporter) {
+
+	if reporter == nil {
+		fallback.Store(nil)
+		return
+	}
+
+	fallback.Store(&reporter)
+}
+
+// wrappingReporter is implemented by derp's own reporter wrappers (FilterBySeverity,
+// FilterByTag, and TimeoutReporter), so that deliveries, statistics, and ConfigurePlugins
+// can reach the reporter inside of them.
+type wrappingReporter interface {
+	Reporter
+	deliver(err error) error
+	innerReporter() Reporter
+}
+
+// deliver sends an error to a single reporter, isolating the caller from its panics
+// (and from its delays, when it is a TimeoutReporter).  Failures are recorded in the
+// reporter's statistics, and described to the fallback reporter.
+func deliver(reporter Reporter, stats *reporterStats, err error) {
+
+	started := time.Now()
+	failure := deliverTo(reporter, err)
+
+	if stats != nil {
+		stats.record(time.Since(started), failure)
+	}
+
+	if failure != nil {
+		reportFailure(failure)
+	}
+}
+
+// deliverTo sends an error to a reporter, and returns an error if the delivery failed.
+// Wrappers forward the delivery, so that a TimeoutReporter inside of a filter still
+// reports its timeouts.
+func deliverTo(reporter Reporter, err error) error {
+
+	if wrapper, ok := reporter.(wrappingReporter); ok {
+		return wrapper.deliver(err)
+	}
+
+	return safeReport(reporter, err)
+}
+
+// safeReport calls a reporter, and converts any panic into an error.
+func safeReport(reporter Reporter, err error) (failure error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			failure = deliveryError(reporter, err, "Reporter panicked", errReporterPanicked, WithField("panic", fmt.Sprint(recovered)))
+		}
+	}()
+
+	reporter.Report(err)
+	return nil
+}
+
+// deliveryError describes a failed delivery of `err` to a reporter.
+func deliveryError(reporter Reporter, err error, message string, cause error, options ...Option) Error {
+
+	result := Internal("derp.Report", message,
+		WithWrappedValue(cause),
+		WithField("reporter", reporterName(reporter)),
+		WithField("error", err.Error()),
+	)
+
+	for _, option := range options {
+		option(&result)
+	}
+
+	return result
+}
+
+// reportFailure sends a failed delivery to the fallback reporter.  Failures of the
+// fallback reporter itself are ignored, so that reporting can never crash the caller.
+func reportFailure(failure error) {
+
+	defer func() {
+		_ = recover()
+	}()
+
+	if reporter := fallback.Load(); reporter != nil {
+		(*reporter).Report(failure)
+		return
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "derp: %s %v\n", failure.Error(), AllFields(failure))
+}
+
+// reporterName describes a reporter in statistics and failure reports, using the
+// type of the reporter inside of any filters and timeouts.
+func reporterName(reporter Reporter) string {
+
+	if wrapper, ok := reporter.(wrappingReporter); ok {
+		return reporterName(wrapper.innerReporter())
+	}
+
+	return fmt.Sprintf("%T", reporter)
+}
+
+/******************************************
+ * Timeouts
+ ******************************************/
+
+// maxPendingDeliveries is the number of deliveries that a TimeoutReporter
+// allows to run at once, including those that have already timed out.
+const maxPendingDeliveries = 64
+
+// TimeoutReporter returns a Reporter that waits at most `timeout` for the provided reporter,
+// so that a hung reporter (such as one writing to an unreachable server) cannot block the
+// caller of Report.  A reporter that runs out of time keeps running in the background, and
+// is counted as a failure in the statistics of its ReporterList.  To keep a hung reporter
+// from piling up goroutines, errors are dropped (and counted as Dropped) while 64
+// deliveries to it are still running.  A timeout of zero or less means "no timeout",
+// so the reporter is returned unchanged.
+func TimeoutReporter(timeout time.Duration, reporter Reporter) Reporter {
+
+	if timeout <= 0 {
+		return reporter
+	}
+
+	return &timeoutReporter{
+		timeout:  timeout,
+		reporter: reporter,
+		pending:  make(chan struct{}, maxPendingDeliveries),
+	}
+}
+
+// timeoutReporter is a Reporter that stops waiting for another reporter after a timeout.
+type timeoutReporter struct {
+	timeout  time.Duration
+	reporter Reporter
+	pending  chan struct{} // semaphore that holds one slot for each running delivery
+}
+
+// Report implements the Reporter interface.  When it is called directly (instead
+// of through a ReporterList), failures still reach the fallback reporter.
+func (bounded *timeoutReporter) Report(err error) {
+
+	if failure := bounded.deliver(err); failure != nil {
+		reportFailure(failure)
+	}
+}
+
+// innerReporter returns the reporter that this one waits for.
+func (bounded *timeoutReporter) innerReporter() Reporter {
+	return bounded.reporter
+}
+
+// deliver calls the reporter on a separate goroutine, and returns an error if it
+// panics, if it does not finish before the timeout, or if too many deliveries are running.
+func (bounded *timeoutReporter) deliver(err error) error {
+
+	// RULE: Never wait for a slot.  Blocking here would hand the hung reporter's delay back to the caller.
+	select {
+	case bounded.pending <- struct{}{}:
+	default:
+		return deliveryError(bounded.reporter, err, "Reporter overloaded", errReporterOverloaded, WithField("pending", cap(bounded.pending)))
+	}
+
+	// Buffered, so that a reporter that finishes late does not leak its goroutine forever
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() { <-bounded.pending }()
+		done <- deliverTo(bounded.reporter, err)
+	}()
+
+	timer := time.NewTimer(bounded.timeout)
+	defer timer.Stop()
+
+	select {
+	case failure := <-done:
+		return failure
+
+	case <-timer.C:
+		return deliveryError(bounded.reporter, err, "Reporter timed out", errReporterTimedOut, WithField("timeout", bounded.timeout.String()))
+	}
+}
+
+/******************************************
+ * Statistics
+ ******************************************/
+
+// ReporterStats describes how deliveries to one reporter in a ReporterList have gone.
+type ReporterStats struct {
+	Name         string        // Type of the reporter, such as "plugins.JSON"
+	Successes    int64         // Errors delivered successfully
+	Failures     int64         // Errors that were not delivered, including panics, timeouts, and drops
+	Panics       int64         // Deliveries that ended in a panic
+	Timeouts     int64         // Deliveries that ran out of time (see TimeoutReporter)
+	Dropped      int64         // Errors dropped because too many deliveries were still running (see TimeoutReporter)
+	TotalLatency time.Duration // Time spent in every delivery
+	MaxLatency   time.Duration // Time spent in the slowest delivery
+}
+
+// Deliveries returns the number of errors sent to the reporter.
+func (stats ReporterStats) Deliveries() int64 {
+	return stats.Successes + stats.Failures
+}
+
+// AverageLatency returns the average time spent in each delivery.
+func (stats ReporterStats) AverageLatency() time.Duration {
+
+	if deliveries := stats.Deliveries(); deliveries > 0 {
+		return stats.TotalLatency / time.Duration(deliveries)
+	}
+
+	return 0
+}
+
+// Stats returns the delivery statistics of every reporter in this list, in the same order
+// as the reporters.  Statistics start from zero when a reporter is added with Set or Add.
+func (list *ReporterList) Stats() []ReporterStats {
+
+	set := list.set()
+	result := make([]ReporterStats, len(set.stats))
+
+	for index, stats := range set.stats {
+		result[index] = stats.snapshot()
+	}
+
+	return result
+}
+
+// reporterStats collects the statistics of one reporter.  It is updated concurrently by
+// every goroutine that reports an error, so every counter is atomic.
+type reporterStats struct {
+	name         string
+	successes    atomic.Int64
+	failures     atomic.Int64
+	panics       atomic.Int64
+	timeouts     atomic.Int64
+	dropped      atomic.Int64
+	totalLatency atomic.Int64
+	maxLatency   atomic.Int64
+}
+
+// newReporterStats returns empty statistics for a reporter.
+func newReporterStats(reporter Reporter) *reporterStats {
+	return &reporterStats{name: reporterName(reporter)}
+}
+
+// record adds the outcome of one delivery.
+func (stats *reporterStats) record(latency time.Duration, failure error) {
+
+	stats.totalLatency.Add(int64(latency))
+
+	for {
+		current := stats.maxLatency.Load()
+
+		if int64(latency) <= current || stats.maxLatency.CompareAndSwap(current, int64(latency)) {
+			break
+		}
+	}
+
+	if failure == nil {
+		stats.successes.Add(1)
+		return
+	}
+
+	stats.failures.Add(1)
+
+	switch {
+	case errors.Is(failure, errReporterPanicked):
+		stats.panics.Add(1)
+	case errors.Is(failure, errReporterTimedOut):
+		stats.timeouts.Add(1)
+	case errors.Is(failure, errReporterOverloaded):
+		stats.dropped.Add(1)
+	}
+}
+
+// snapshot returns a copy of the current statistics.
+func (stats *reporterStats) snapshot() ReporterStats {
+	return ReporterStats{
+		Name:         stats.name,
+		Successes:    stats.successes.Load(),
+		Failures:     stats.failures.Load(),
+		Panics:       stats.panics.Load(),
+		Timeouts:     stats.timeouts.Load(),
+		Dropped:      stats.dropped.Load(),
+		TotalLatency: time.Duration(stats.totalLatency.Load()),
+		MaxLatency:   time.Duration(stats.maxLatency.Load()),
+	}
+}
@@ -0,0 +1,216 @@
+package derp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// panickingPlugin panics whenever it is asked to report an error.
+type panickingPlugin struct{}
+
+func (panickingPlugin) Report(error) {
+	panic("boom")
+}
+
+// blockingPlugin blocks until its channel is closed.
+type blockingPlugin struct {
+	release chan struct{}
+}
+
+func (plugin blockingPlugin) Report(error) {
+	<-plugin.release
+}
+
+// lockedRecordingPlugin remembers every error it receives, and is safe for concurrent use.
+type lockedRecordingPlugin struct {
+	lock   sync.Mutex
+	errors []error
+}
+
+func (plugin *lockedRecordingPlugin) Report(err error) {
+	plugin.lock.Lock()
+	defer plugin.lock.Unlock()
+	plugin.errors = append(plugin.errors, err)
+}
+
+func (plugin *lockedRecordingPlugin) recorded() []error {
+	plugin.lock.Lock()
+	defer plugin.lock.Unlock()
+	return append([]error{}, plugin.errors...)
+}
+
+// useFallback replaces the fallback reporter for the duration of a test.
+func useFallback(t *testing.T) *lockedRecordingPlugin {
+
+	result := &lockedRecordingPlugin{}
+	SetFallbackReporter(result)
+	t.Cleanup(func() { SetFallbackReporter(nil) })
+
+	return result
+}
+
+func TestFanout_Panic(t *testing.T) {
+
+	fallbackReporter := useFallback(t)
+	after := &countingPlugin{}
+
+	var list ReporterList
+	list.Set(panickingPlugin{}, after)
+
+	// The panic is isolated, and later reporters still run
+	require.NotPanics(t, func() {
+		report(&list, NotFound("location", "message"))
+	})
+
+	require.Equal(t, 1, after.count)
+
+	failures := fallbackReporter.recorded()
+	require.Len(t, failures, 1)
+	require.Equal(t, "Reporter panicked", Message(failures[0]))
+	require.Equal(t, "boom", AllFields(failures[0])["panic"])
+	require.Equal(t, "derp.panickingPlugin", AllFields(failures[0])["reporter"])
+}
+
+func TestFanout_PanickingFallback(t *testing.T) {
+
+	SetFallbackReporter(panickingPlugin{})
+	t.Cleanup(func() { SetFallbackReporter(nil) })
+
+	var list ReporterList
+	list.Set(panickingPlugin{})
+
+	require.NotPanics(t, func() {
+		report(&list, NotFound("location", "message"))
+	})
+}
+
+func TestFanout_Timeout(t *testing.T) {
+
+	fallbackReporter := useFallback(t)
+	blocked := blockingPlugin{release: make(chan struct{})}
+	t.Cleanup(func() { close(blocked.release) })
+
+	after := &countingPlugin{}
+
+	var list ReporterList
+	list.Set(TimeoutReporter(10*time.Millisecond, blocked), after)
+
+	started := time.Now()
+	report(&list, NotFound("location", "message"))
+
+	require.Less(t, time.Since(started), time.Second)
+	require.Equal(t, 1, after.count)
+
+	failures := fallbackReporter.recorded()
+	require.Len(t, failures, 1)
+	require.Equal(t, "Reporter timed out", Message(failures[0]))
+	require.Equal(t, "derp.blockingPlugin", AllFields(failures[0])["reporter"])
+}
+
+func TestFanout_TimeoutReporterDirect(t *testing.T) {
+
+	fallbackReporter := useFallback(t)
+
+	// Fast reporters finish normally
+	counter := &countingPlugin{}
+	TimeoutReporter(time.Second, counter).Report(NotFound("location", "message"))
+	require.Equal(t, 1, counter.count)
+
+	// Panics inside a TimeoutReporter are isolated too
+	TimeoutReporter(time.Second, panickingPlugin{}).Report(NotFound("location", "message"))
+	require.Len(t, fallbackReporter.recorded(), 1)
+}
+
+func TestFanout_TimeoutReporterNoTimeout(t *testing.T) {
+
+	// Timeouts of zero or less mean "no timeout", instead of timing out every delivery
+	for _, timeout := range []time.Duration{0, -time.Second} {
+
+		counter := &countingPlugin{}
+		require.Same(t, counter, TimeoutReporter(timeout, counter))
+
+		var list ReporterList
+		list.Set(TimeoutReporter(timeout, counter))
+		report(&list, NotFound("location", "message"))
+
+		require.Equal(t, 1, counter.count)
+		require.Equal(t, int64(1), list.Stats()[0].Successes)
+	}
+}
+
+func TestFanout_TimeoutReporterBounded(t *testing.T) {
+
+	fallbackReporter := useFallback(t)
+	blocked := blockingPlugin{release: make(chan struct{})}
+
+	var list ReporterList
+	list.Set(TimeoutReporter(time.Millisecond, blocked))
+
+	// Every delivery to the hung reporter times out, and keeps running
+	for index := 0; index < maxPendingDeliveries; index++ {
+		report(&list, NotFound("location", "message"))
+	}
+
+	// Further errors are dropped immediately, without starting another goroutine
+	report(&list, NotFound("location", "message"))
+
+	stats := list.Stats()[0]
+	require.Equal(t, int64(maxPendingDeliveries), stats.Timeouts)
+	require.Equal(t, int64(1), stats.Dropped)
+	require.Equal(t, int64(maxPendingDeliveries+1), stats.Failures)
+
+	failures := fallbackReporter.recorded()
+	require.Equal(t, "Reporter overloaded", Message(failures[len(failures)-1]))
+
+	// Once the reporter recovers, its slots are released
+	close(blocked.release)
+	require.Eventually(t, func() bool {
+		report(&list, NotFound("location", "message"))
+		return list.Stats()[0].Successes > 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestFanout_Stats(t *testing.T) {
+
+	useFallback(t)
+
+	blocked := blockingPlugin{release: make(chan struct{})}
+	t.Cleanup(func() { close(blocked.release) })
+
+	var list ReporterList
+	list.Set(&countingPlugin{}, panickingPlugin{})
+	list.Add(TimeoutReporter(time.Millisecond, blocked))
+
+	report(&list, NotFound("location", "message"))
+	report(&list, NotFound("location", "message"))
+
+	stats := list.Stats()
+	require.Len(t, stats, 3)
+
+	require.Equal(t, "*derp.countingPlugin", stats[0].Name)
+	require.Equal(t, int64(2), stats[0].Successes)
+	require.Equal(t, int64(0), stats[0].Failures)
+
+	require.Equal(t, "derp.panickingPlugin", stats[1].Name)
+	require.Equal(t, int64(2), stats[1].Failures)
+	require.Equal(t, int64(2), stats[1].Panics)
+
+	require.Equal(t, "derp.blockingPlugin", stats[2].Name)
+	require.Equal(t, int64(2), stats[2].Timeouts)
+	require.Equal(t, int64(2), stats[2].Deliveries())
+	require.GreaterOrEqual(t, stats[2].MaxLatency, time.Millisecond)
+	require.Positive(t, stats[2].AverageLatency())
+
+	// Adding a reporter keeps the history of the others
+	list.Add(&countingPlugin{})
+	require.Equal(t, int64(2), list.Stats()[0].Successes)
+
+	// ...while Set starts over
+	list.Set(&countingPlugin{})
+	require.Equal(t, int64(0), list.Stats()[0].Successes)
+
+	require.Equal(t, time.Duration(0), ReporterStats{}.AverageLatency())
+}
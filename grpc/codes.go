@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"github.com/benpate/derp"
+	"google.golang.org/grpc/codes"
+)
+
+// codeTimeout is derp's unofficial (524) Timeout code.
+const codeTimeout = 524
+
+// ToCode maps a derp error code onto the closest gRPC code.  Registered application
+// codes (see derp.RegisterCode) are mapped by the HTTP status that they map onto.
+// https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func ToCode(code int) codes.Code {
+
+	if info, ok := derp.LookupCode(code); ok && info.HTTPStatus != 0 {
+		code = info.HTTPStatus
+	}
+
+	switch code {
+
+	case 0:
+		return codes.OK
+
+	case 400:
+		return codes.InvalidArgument
+
+	case 401:
+		return codes.Unauthenticated
+
+	case 403:
+		return codes.PermissionDenied
+
+	case 404, 410:
+		return codes.NotFound
+
+	case 409:
+		return codes.AlreadyExists
+
+	case 412, 421:
+		return codes.FailedPrecondition
+
+	case 416:
+		return codes.OutOfRange
+
+	case 418, 501:
+		return codes.Unimplemented
+
+	case 422:
+		return codes.InvalidArgument
+
+	case 429:
+		return codes.ResourceExhausted
+
+	case derp.CodeClientClosedRequest:
+		return codes.Canceled
+
+	case 500:
+		return codes.Internal
+
+	case 502, 503:
+		return codes.Unavailable
+
+	case 504, codeTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	// Unrecognized codes are mapped by class
+	switch {
+	case code >= 400 && code < 500:
+		return codes.FailedPrecondition
+
+	case code >= 500 && code < 600:
+		return codes.Internal
+	}
+
+	return codes.Unknown
+}
+
+// FromCode maps a gRPC code onto the closest derp error code.
+// https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func FromCode(code codes.Code) int {
+
+	switch code {
+
+	case codes.OK:
+		return 0
+
+	case codes.Canceled:
+		return derp.CodeClientClosedRequest
+
+	case codes.InvalidArgument:
+		return 422
+
+	case codes.DeadlineExceeded:
+		return codeTimeout
+
+	case codes.NotFound:
+		return 404
+
+	case codes.AlreadyExists, codes.Aborted:
+		return 409
+
+	case codes.PermissionDenied:
+		return 403
+
+	case codes.ResourceExhausted:
+		return 429
+
+	case codes.FailedPrecondition, codes.OutOfRange:
+		return 400
+
+	case codes.Unimplemented:
+		return 501
+
+	case codes.Unavailable:
+		return 503
+
+	case codes.Unauthenticated:
+		return 401
+	}
+
+	// Unknown, Internal, DataLoss, and anything unrecognized
+	return 500
+}
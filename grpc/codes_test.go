@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestToCode(t *testing.T) {
+
+	require.Equal(t, codes.OK, ToCode(0))
+	require.Equal(t, codes.InvalidArgument, ToCode(400))
+	require.Equal(t, codes.Unauthenticated, ToCode(401))
+	require.Equal(t, codes.PermissionDenied, ToCode(403))
+	require.Equal(t, codes.NotFound, ToCode(404))
+	require.Equal(t, codes.AlreadyExists, ToCode(409))
+	require.Equal(t, codes.NotFound, ToCode(410))
+	require.Equal(t, codes.InvalidArgument, ToCode(422))
+	require.Equal(t, codes.ResourceExhausted, ToCode(429))
+	require.Equal(t, codes.Canceled, ToCode(499))
+	require.Equal(t, codes.Internal, ToCode(500))
+	require.Equal(t, codes.Unimplemented, ToCode(501))
+	require.Equal(t, codes.Unavailable, ToCode(502))
+	require.Equal(t, codes.Unavailable, ToCode(503))
+	require.Equal(t, codes.DeadlineExceeded, ToCode(504))
+	require.Equal(t, codes.DeadlineExceeded, ToCode(524))
+
+	// Unrecognized codes are mapped by class
+	require.Equal(t, codes.FailedPrecondition, ToCode(451))
+	require.Equal(t, codes.Internal, ToCode(599))
+	require.Equal(t, codes.Unknown, ToCode(123))
+}
+
+func TestToCode_RegisteredCode(t *testing.T) {
+
+	// Registered application codes are mapped by their HTTP status
+	require.Nil(t, derp.RegisterCode(derp.CodeInfo{Code: 40001, Name: "grpc_test.not_found", HTTPStatus: 404}))
+	t.Cleanup(func() { derp.UnregisterCode(40001) })
+
+	require.Equal(t, codes.NotFound, ToCode(40001))
+
+	require.Equal(t, codes.Unknown, ToCode(40002))
+}
+
+func TestFromCode(t *testing.T) {
+
+	require.Equal(t, 0, FromCode(codes.OK))
+	require.Equal(t, 499, FromCode(codes.Canceled))
+	require.Equal(t, 500, FromCode(codes.Unknown))
+	require.Equal(t, 422, FromCode(codes.InvalidArgument))
+	require.Equal(t, 524, FromCode(codes.DeadlineExceeded))
+	require.Equal(t, 404, FromCode(codes.NotFound))
+	require.Equal(t, 409, FromCode(codes.AlreadyExists))
+	require.Equal(t, 403, FromCode(codes.PermissionDenied))
+	require.Equal(t, 429, FromCode(codes.ResourceExhausted))
+	require.Equal(t, 400, FromCode(codes.FailedPrecondition))
+	require.Equal(t, 409, FromCode(codes.Aborted))
+	require.Equal(t, 400, FromCode(codes.OutOfRange))
+	require.Equal(t, 501, FromCode(codes.Unimplemented))
+	require.Equal(t, 500, FromCode(codes.Internal))
+	require.Equal(t, 503, FromCode(codes.Unavailable))
+	require.Equal(t, 500, FromCode(codes.DataLoss))
+	require.Equal(t, 401, FromCode(codes.Unauthenticated))
+}
+
+// TestCode_RoundTrip confirms that the codes derp uses most often survive a round trip.
+func TestCode_RoundTrip(t *testing.T) {
+
+	for _, code := range []int{401, 403, 404, 409, 422, 429, 499, 500, 501, 503, 524} {
+		require.Equal(t, code, FromCode(ToCode(code)), "code %d", code)
+	}
+}
@@ -0,0 +1,7 @@
+// Package grpc converts derp errors to and from gRPC statuses.  It maps derp's HTTP-style
+// error codes onto gRPC codes (and back), carries each error's location and details in the
+// status details, and provides server and client interceptors that do this automatically.
+//
+// This package is a separate module, so that applications that do not use gRPC
+// do not inherit its dependencies.
+package grpc
@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/benpate/derp"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerInterceptor returns a server interceptor that reports every error returned by a
+// unary handler (via derp.ReportContext), and converts it into a gRPC status with ToStatus.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+
+	return func(ctx context.Context, request any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+
+		response, err := handler(ctx, request)
+
+		if err != nil {
+			return response, serverError(ctx, err)
+		}
+
+		return response, nil
+	}
+}
+
+// StreamServerInterceptor returns a server interceptor that reports every error returned by a
+// streaming handler (via derp.ReportContext), and converts it into a gRPC status with ToStatus.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+
+	return func(server any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := handler(server, stream); err != nil {
+			return serverError(stream.Context(), err)
+		}
+
+		return nil
+	}
+}
+
+// UnaryClientInterceptor returns a client interceptor that converts the gRPC status of a
+// failed call into a derp.Error with FromError, located at the full method name.  Errors are
+// not reported here, because the caller decides whether a failed call is worth reporting.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+
+	return func(ctx context.Context, method string, request, reply any, connection *grpc.ClientConn, invoker grpc.UnaryInvoker, options ...grpc.CallOption) error {
+		return FromError(invoker(ctx, method, request, reply, connection, options...), method)
+	}
+}
+
+// StreamClientInterceptor returns a client interceptor that converts the gRPC statuses of a
+// failed stream into derp.Errors with FromError, located at the full method name.  The io.EOF
+// that ends a stream normally is passed through unchanged.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+
+	return func(ctx context.Context, description *grpc.StreamDesc, connection *grpc.ClientConn, method string, streamer grpc.Streamer, options ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		stream, err := streamer(ctx, description, connection, method, options...)
+
+		if err != nil {
+			return nil, FromError(err, method)
+		}
+
+		return clientStream{ClientStream: stream, method: method}, nil
+	}
+}
+
+// clientStream converts the errors of an underlying ClientStream into derp.Errors.
+type clientStream struct {
+	grpc.ClientStream
+	method string
+}
+
+// SendMsg implements the grpc.ClientStream interface.
+func (stream clientStream) SendMsg(message any) error {
+	return stream.convert(stream.ClientStream.SendMsg(message))
+}
+
+// RecvMsg implements the grpc.ClientStream interface.
+func (stream clientStream) RecvMsg(message any) error {
+	return stream.convert(stream.ClientStream.RecvMsg(message))
+}
+
+// convert converts a stream error, leaving io.EOF untouched so that callers can still detect it.
+func (stream clientStream) convert(err error) error {
+
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return FromError(err, stream.method)
+}
+
+// serverError reports an error returned by a handler to the derp.Scope of its context
+// (or to the global reporters), and converts it into a status error.
+func serverError(ctx context.Context, err error) error {
+	derp.ReportContext(ctx, err)
+	return ToStatus(err).Err()
+}
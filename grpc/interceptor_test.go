@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// failingHealthServer returns derp errors from every method.
+type failingHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func (failingHealthServer) Check(context.Context, *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	return nil, derp.NotFound("health.Check", "Unknown service", "billing")
+}
+
+func (failingHealthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+
+	if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+		return err
+	}
+
+	return derp.Timeout("health.Watch", "Upstream timed out")
+}
+
+// recordingReporter remembers every error that derp reports.
+type recordingReporter struct {
+	lock   sync.Mutex
+	errors []error
+}
+
+func (reporter *recordingReporter) Report(err error) {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+	reporter.errors = append(reporter.errors, err)
+}
+
+func (reporter *recordingReporter) count() int {
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+	return len(reporter.errors)
+}
+
+// newTestClient starts an in-memory server with the derp interceptors installed,
+// and returns a health client connected to it with the derp client interceptors.
+func newTestClient(t *testing.T) grpc_health_v1.HealthClient {
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+		grpc.StreamInterceptor(StreamServerInterceptor()),
+	)
+
+	grpc_health_v1.RegisterHealthServer(server, failingHealthServer{})
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	connection, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = connection.Close() })
+
+	return grpc_health_v1.NewHealthClient(connection)
+}
+
+// useReporter replaces the derp reporters for the duration of a test.
+func useReporter(t *testing.T) *recordingReporter {
+
+	reporter := &recordingReporter{}
+	derp.SetPlugins(reporter)
+	t.Cleanup(func() { derp.SetPlugins() })
+
+	return reporter
+}
+
+func TestInterceptor_Unary(t *testing.T) {
+
+	reporter := useReporter(t)
+	client := newTestClient(t)
+
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.Error(t, err)
+
+	// The client receives a derp error with the server's code, location, and details
+	require.True(t, derp.IsNotFound(err))
+	require.Equal(t, "health.Check", derp.Location(err))
+	require.Equal(t, "Unknown service", derp.Message(err))
+	require.Equal(t, []any{"billing"}, derp.Details(err))
+
+	// ...which still carries the original gRPC status
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// The server reported the error
+	require.Equal(t, 1, reporter.count())
+}
+
+func TestInterceptor_Stream(t *testing.T) {
+
+	reporter := useReporter(t)
+	client := newTestClient(t)
+
+	stream, err := client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	response, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+
+	_, err = stream.Recv()
+	require.Error(t, err)
+	require.False(t, errors.Is(err, io.EOF))
+	require.Equal(t, 524, derp.ErrorCode(err))
+	require.Equal(t, "health.Watch", derp.Location(err))
+	require.Equal(t, 1, reporter.count())
+}
+
+func TestInterceptor_Unimplemented(t *testing.T) {
+
+	useReporter(t)
+	client := newTestClient(t)
+
+	// Plain status errors from the server pass through unchanged
+	_, err := client.List(context.Background(), &grpc_health_v1.HealthListRequest{})
+	require.True(t, derp.IsNotImplemented(err))
+	require.Equal(t, "/grpc.health.v1.Health/List", derp.Location(err))
+}
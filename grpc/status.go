@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/benpate/derp"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorInfoDomain identifies the ErrorInfo details that this package writes into a status.
+const errorInfoDomain = "derp"
+
+// Metadata keys used in the ErrorInfo details of a status.
+const (
+	metadataCode     = "code"
+	metadataID       = "id"
+	metadataLocation = "location"
+	metadataURL      = "url"
+	metadataDetail   = "detail."
+)
+
+// grpcStatuser is implemented by errors that already carry a gRPC status,
+// including every error created by the status package.
+type grpcStatuser interface {
+	GRPCStatus() *status.Status
+}
+
+// ToStatus converts any error into a gRPC status.  The derp error code is mapped with ToCode
+// (canceled and expired contexts become codes.Canceled and codes.DeadlineExceeded), and the ID, location, URL, and details of the error are carried in an ErrorInfo detail, along
+// with a RetryInfo detail when the error has a retry-after duration.  Errors that already
+// carry a gRPC status are returned unchanged.  Sensitive values are removed by derp's
+// Redactor first, because a status leaves the process.
+func ToStatus(err error) *status.Status {
+
+	// double nil check to make nilaway happy
+	if derp.IsNil(err) || err == nil {
+		return status.New(codes.OK, "")
+	}
+
+	if statuser, ok := err.(grpcStatuser); ok {
+		return statuser.GRPCStatus()
+	}
+
+	code := errorCode(err)
+	err = derp.Redact(err)
+
+	result := status.New(ToCode(code), derp.Message(err))
+
+	errorInfo := &errdetails.ErrorInfo{
+		Reason: strconv.Itoa(code),
+		Domain: errorInfoDomain,
+		Metadata: map[string]string{
+			metadataCode: strconv.Itoa(code),
+		},
+	}
+
+	if id := derp.ErrorID(err); id != "" {
+		errorInfo.Metadata[metadataID] = id
+	}
+
+	if location := derp.Location(err); location != "" {
+		errorInfo.Metadata[metadataLocation] = location
+	}
+
+	if url := derp.URL(err); url != "" {
+		errorInfo.Metadata[metadataURL] = url
+	}
+
+	for index, detail := range derp.Details(err) {
+		errorInfo.Metadata[metadataDetail+strconv.Itoa(index)] = detailString(detail)
+	}
+
+	details := []protoadapt.MessageV1{errorInfo}
+
+	if retryAfter := derp.RetryAfter(err); retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+
+	// Details that cannot be attached are dropped, but the status itself is still valid.
+	if withDetails, detailsError := result.WithDetails(details...); detailsError == nil {
+		return withDetails
+	}
+
+	return result
+}
+
+// errorCode returns the derp error code of any error.  Context errors that do not carry a more
+// specific code are (499) Client Closed Request or (524) Timeout errors, instead of (500) Internal.
+func errorCode(err error) int {
+
+	code := derp.ErrorCode(err)
+
+	if code != http.StatusInternalServerError {
+		return code
+	}
+
+	switch {
+
+	case errors.Is(err, context.Canceled):
+		return derp.CodeClientClosedRequest
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return codeTimeout
+	}
+
+	return code
+}
+
+// FromError converts an error returned by a gRPC call into a derp.Error.  Errors that do not
+// carry a gRPC status (anywhere in their chain) are returned unchanged.  The location is read from the status details
+// when the server used this package; otherwise the provided location is used.
+func FromError(err error, location string) error {
+
+	// double nil check to make nilaway happy
+	if derp.IsNil(err) || err == nil {
+		return nil
+	}
+
+	statuser, ok := derp.AsType[grpcStatuser](err)
+
+	if !ok {
+		return err
+	}
+
+	result := FromStatus(statuser.GRPCStatus(), location)
+	result.WrappedValue = err
+	return result
+}
+
+// FromStatus converts a gRPC status into a derp.Error.  The gRPC code is mapped with FromCode,
+// unless the status details carry the original derp code.  The location is read from the
+// status details when available; otherwise the provided location is used.
+func FromStatus(value *status.Status, location string) derp.Error {
+
+	result := derp.Error{
+		Code:      FromCode(value.Code()),
+		Location:  location,
+		Message:   value.Message(),
+		TimeStamp: derp.Now(),
+	}
+
+	for _, detail := range value.Details() {
+
+		switch typed := detail.(type) {
+
+		case *errdetails.ErrorInfo:
+
+			if typed.GetDomain() != errorInfoDomain {
+				continue
+			}
+
+			metadata := typed.GetMetadata()
+
+			if code, parseError := strconv.Atoi(metadata[metadataCode]); parseError == nil {
+				result.Code = code
+			}
+
+			if remoteLocation := metadata[metadataLocation]; remoteLocation != "" {
+				result.Location = remoteLocation
+			}
+
+			result.ID = metadata[metadataID]
+			result.URL = metadata[metadataURL]
+
+			for index := 0; ; index++ {
+				detail, ok := metadata[metadataDetail+strconv.Itoa(index)]
+
+				if !ok {
+					break
+				}
+
+				result.Details = append(result.Details, detail)
+			}
+
+		case *errdetails.RetryInfo:
+			result.RetryAfter = typed.GetRetryDelay().AsDuration()
+		}
+	}
+
+	return result
+}
+
+// detailString converts a single derp detail into the string form required by ErrorInfo metadata.
+func detailString(detail any) string {
+
+	switch typed := detail.(type) {
+
+	case string:
+		return typed
+
+	case fmt.Stringer:
+		return typed.String()
+
+	case error:
+		return typed.Error()
+	}
+
+	if bytes, err := json.Marshal(detail); err == nil {
+		return string(bytes)
+	}
+
+	return fmt.Sprint(detail)
+}
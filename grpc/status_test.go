@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+
+	err := derp.Conflict("users.Create", "Email already registered", "alice", derp.WithRetryAfter(time.Minute), derp.WithID("0190b3a4-7c1e-7000-8000-000000000000"))
+	result := ToStatus(err)
+
+	require.Equal(t, codes.AlreadyExists, result.Code())
+	require.Equal(t, "Email already registered", result.Message())
+	require.Len(t, result.Details(), 2)
+
+	// Everything survives a round trip through FromStatus
+	converted := FromStatus(result, "client")
+	require.Equal(t, 409, converted.Code)
+	require.Equal(t, "0190b3a4-7c1e-7000-8000-000000000000", converted.ID)
+	require.Equal(t, "users.Create", converted.Location)
+	require.Equal(t, "Email already registered", converted.Message)
+	require.Equal(t, []any{"alice"}, converted.Details)
+	require.Equal(t, time.Minute, converted.RetryAfter)
+}
+
+func TestToStatus_Nil(t *testing.T) {
+	require.Equal(t, codes.OK, ToStatus(nil).Code())
+}
+
+func TestToStatus_Standard(t *testing.T) {
+
+	result := ToStatus(errors.New("something broke"))
+	require.Equal(t, codes.Internal, result.Code())
+	require.Equal(t, "something broke", result.Message())
+}
+
+func TestToStatus_Context(t *testing.T) {
+
+	require.Equal(t, codes.Canceled, ToStatus(context.Canceled).Code())
+	require.Equal(t, codes.Canceled, ToStatus(derp.Wrap(context.Canceled, "location", "Request abandoned")).Code())
+	require.Equal(t, codes.DeadlineExceeded, ToStatus(context.DeadlineExceeded).Code())
+	require.Equal(t, codes.DeadlineExceeded, ToStatus(fmt.Errorf("query: %w", context.DeadlineExceeded)).Code())
+
+	// More specific codes still win
+	require.Equal(t, codes.Unavailable, ToStatus(derp.BadGateway("location", "Unavailable", derp.WithWrappedValue(context.Canceled))).Code())
+
+	// The original code survives a round trip
+	require.Equal(t, 499, FromStatus(ToStatus(context.Canceled), "").Code)
+}
+
+func TestToStatus_PassThrough(t *testing.T) {
+
+	original := status.Error(codes.DataLoss, "disk corrupt")
+	require.Equal(t, codes.DataLoss, ToStatus(original).Code())
+}
+
+func TestToStatus_Redacted(t *testing.T) {
+
+	err := derp.Internal("location", "failed for user@example.com", map[string]any{"password": "hunter2"})
+	converted := FromStatus(ToStatus(err), "")
+
+	require.NotContains(t, converted.Message, "user@example.com")
+	require.NotContains(t, converted.Details[0], "hunter2")
+}
+
+func TestFromStatus_Foreign(t *testing.T) {
+
+	// Statuses from servers that do not use derp are mapped by code alone
+	converted := FromStatus(status.New(codes.NotFound, "no such user"), "client.GetUser")
+
+	require.Equal(t, 404, converted.Code)
+	require.Equal(t, "client.GetUser", converted.Location)
+	require.Equal(t, "no such user", converted.Message)
+	require.True(t, derp.IsNotFound(converted))
+}
+
+func TestFromError(t *testing.T) {
+
+	require.Nil(t, FromError(nil, "location"))
+
+	// Errors without a status are returned unchanged
+	standard := errors.New("standard")
+	require.Equal(t, standard, FromError(standard, "location"))
+
+	// Status errors are converted, and remain reachable through the chain
+	original := status.Error(codes.Unavailable, "try later")
+	converted := FromError(original, "location")
+
+	require.Equal(t, 503, derp.ErrorCode(converted))
+	require.True(t, errors.Is(converted, original))
+}
+
+func TestFromError_Wrapped(t *testing.T) {
+
+	// Statuses are found anywhere in the chain
+	original := status.Error(codes.NotFound, "no such user")
+	converted := FromError(fmt.Errorf("client.GetUser: %w", original), "location")
+
+	require.Equal(t, 404, derp.ErrorCode(converted))
+	require.True(t, errors.Is(converted, original))
+}
@@ -0,0 +1,74 @@
+package derp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+// generateIDs is TRUE when every new error receives a unique ID automatically.
+var generateIDs atomic.Bool
+
+// SetGenerateIDs controls whether every new error receives a unique ID automatically.
+// When disabled (the default), only errors created with WithID carry an ID.
+func SetGenerateIDs(enabled bool) {
+	generateIDs.Store(enabled)
+}
+
+// NewID returns a new, unique error ID in the UUIDv7 format (RFC 9562).  IDs begin
+// with a millisecond timestamp, so they sort in the order they were created, and
+// they are generated locally, without any coordination between servers.
+func NewID() string {
+
+	var id [16]byte
+
+	// 48-bit timestamp, followed by 80 bits of randomness
+	binary.BigEndian.PutUint64(id[0:8], uint64(Now().UnixMilli())<<16)
+
+	// crypto/rand never returns an error on supported platforms
+	_, _ = rand.Read(id[6:])
+
+	id[6] = (id[6] & 0x0f) | 0x70 // version 7
+	id[8] = (id[8] & 0x3f) | 0x80 // RFC 9562 variant
+
+	var result [36]byte
+	hex.Encode(result[0:8], id[0:4])
+	result[8] = '-'
+	hex.Encode(result[9:13], id[4:6])
+	result[13] = '-'
+	hex.Encode(result[14:18], id[6:8])
+	result[18] = '-'
+	hex.Encode(result[19:23], id[8:10])
+	result[23] = '-'
+	hex.Encode(result[24:], id[10:])
+
+	return string(result[:])
+}
+
+// ErrorID retrieves the unique ID of any error.  Wrapped errors share the ID of
+// their root cause, so the outermost error exposes the same ID as the original.
+func ErrorID(err error) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	if getter, ok := AsType[ErrorIDGetter](err); ok {
+		return getter.GetErrorID()
+	}
+
+	return ""
+}
+
+// newErrorID returns the ID for a new error that wraps `inner`: nothing if the inner error
+// already has an ID (which is inherited instead), or if automatic IDs are disabled.
+func newErrorID(inner error) string {
+
+	if !generateIDs.Load() || ErrorID(inner) != "" {
+		return ""
+	}
+
+	return NewID()
+}
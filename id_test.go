@@ -0,0 +1,71 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestID_NewID(t *testing.T) {
+
+	format := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	previous := ""
+
+	for index := 0; index < 100; index++ {
+		id := NewID()
+		require.Regexp(t, format, id)
+		require.NotEqual(t, previous, id)
+
+		// The timestamp prefix keeps IDs in the order they were created
+		require.GreaterOrEqual(t, id[:13], previous[:min(len(previous), 13)])
+		previous = id
+	}
+}
+
+func TestID_Optional(t *testing.T) {
+
+	// IDs are not generated by default
+	require.Equal(t, "", ErrorID(NotFound("location", "message")))
+	require.Equal(t, "", ErrorID(nil))
+	require.Equal(t, "", ErrorID(errors.New("plain")))
+
+	err := NotFound("location", "message", WithID("custom-id"))
+	require.Equal(t, "custom-id", ErrorID(err))
+	require.False(t, Error{ID: "custom-id"}.IsZero())
+}
+
+func TestID_Generated(t *testing.T) {
+
+	SetGenerateIDs(true)
+	t.Cleanup(func() { SetGenerateIDs(false) })
+
+	inner := NotFound("inner", "message")
+	require.NotEmpty(t, inner.ID)
+
+	// Wrapping preserves the ID of the root cause, including through standard wrappers
+	outer := Wrap(fmt.Errorf("context: %w", inner), "outer", "message")
+	require.Empty(t, outer.(Error).ID)
+	require.Equal(t, inner.ID, ErrorID(outer))
+
+	// Errors that wrap non-derp errors are the root, so they receive an ID of their own
+	root := Wrap(errors.New("plain"), "location", "message")
+	require.NotEmpty(t, root.(Error).ID)
+	require.NotEqual(t, inner.ID, ErrorID(root))
+}
+
+func TestID_JSON(t *testing.T) {
+
+	inner := NotFound("inner", "message", WithID("root-id"))
+	outer := Wrap(inner, "outer", "message")
+
+	var result struct {
+		ID string `json:"id"`
+	}
+
+	require.Nil(t, json.Unmarshal([]byte(Serialize(outer)), &result))
+	require.Equal(t, "root-id", result.ID)
+}
@@ -12,9 +12,15 @@ func SetPlugins(reporters ...Reporter) {
 	Plugins.Set(reporters...)
 }
 
-// init populates the default plugin list, so that derp reports errors out of the box.
+// init populates the default plugin list and redaction rules, so that derp reports errors out of the box.
 func init() {
 
 	// Start with the JSON reporter as the only item in the list.
 	Plugins.Set(plugins.JSON{})
+
+	// Redact credentials and personal data by default, including the plain text that plugins write.
+	SetRedactor(NewRedactor())
+	plugins.SetScrubber(func(text string) string {
+		return currentRedactor().Scrub(text)
+	})
 }
@@ -16,6 +16,30 @@ type ErrorCodeGetter interface {
 	GetErrorCode() int
 }
 
+// ErrorIDGetter interface wraps the GetErrorID method, which returns a unique ID that identifies the error
+type ErrorIDGetter interface {
+	// GetErrorID returns a unique ID that customers can quote to support.
+	GetErrorID() string
+}
+
+// FieldsGetter interface wraps the GetFields method, which returns typed key/value pairs that describe the error
+type FieldsGetter interface {
+	// GetFields returns typed key/value pairs (such as "userId") that describe the error.
+	GetFields() map[string]any
+}
+
+// FingerprintGetter interface wraps the GetFingerprint method, which returns an identifier that groups similar errors together
+type FingerprintGetter interface {
+	// GetFingerprint returns a short, stable identifier that groups similar errors together.
+	GetFingerprint() string
+}
+
+// HTTPStatusGetter interface wraps the GetHTTPStatus method, which returns the HTTP status that describes the error to clients
+type HTTPStatusGetter interface {
+	// GetHTTPStatus returns the HTTP status that describes the error to clients (see HTTPStatus).
+	GetHTTPStatus() int
+}
+
 // LocationGetter interface wraps the GetLocation method, which returns the location of the error
 type LocationGetter interface {
 	// GetLocation returns the location of the error in the source code.
@@ -29,12 +53,54 @@ type MessageGetter interface {
 	GetMessage() string
 }
 
+// MessageKeyGetter interface wraps the GetMessageKey method, which returns the catalog key of a user-facing message
+type MessageKeyGetter interface {
+	// GetMessageKey returns the catalog key of a localized, user-facing message.
+	GetMessageKey() string
+}
+
+// PublicMessageGetter interface wraps the GetPublicMessage method, which returns text that is safe to show end users
+type PublicMessageGetter interface {
+	// GetPublicMessage returns text that is safe to show end users.
+	GetPublicMessage() string
+}
+
 // RetryAfterGetter interface wraps the GetRetryAfter method, which returns the number of seconds to wait before retrying the operation that caused this error
 type RetryAfterGetter interface {
 	// GetRetryAfter returns the number of seconds to wait before retrying the operation that caused this error.
 	GetRetryAfter() time.Duration
 }
 
+// RetryableGetter interface wraps the GetRetryable method, which reports whether the operation that caused this error is worth retrying
+type RetryableGetter interface {
+	// GetRetryable returns TRUE if the operation that caused this error is worth retrying.
+	GetRetryable() bool
+}
+
+// SeverityGetter interface wraps the GetSeverity method, which returns how bad this error is
+type SeverityGetter interface {
+	// GetSeverity returns how bad this error is.
+	GetSeverity() Severity
+}
+
+// TagsGetter interface wraps the GetTags method, which returns short labels that describe the error
+type TagsGetter interface {
+	// GetTags returns short labels (such as "billing") that describe the error.
+	GetTags() []string
+}
+
+// TemplateGetter interface wraps the GetTemplate method, which returns the message template of the error
+type TemplateGetter interface {
+	// GetTemplate returns the message of the error, before its placeholders were filled.
+	GetTemplate() string
+}
+
+// TimeStampGetter interface wraps the GetTimeStamp method, which returns the date/time when the error was created
+type TimeStampGetter interface {
+	// GetTimeStamp returns the date/time when the error was created.
+	GetTimeStamp() time.Time
+}
+
 // URLGetter interface wraps the GetURL method, which returns a URL to a web page with more information about this error
 type URLGetter interface {
 	// GetURL returns a URL to a web page with more information about this error.
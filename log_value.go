@@ -0,0 +1,121 @@
+package derp
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// LogValue implements the slog.LogValuer interface, so that derp errors are logged as
+// structured groups rather than flat strings.  Typed fields (merged across the wrap chain)
+// are emitted as attributes of their own, so that log pipelines can index them.  Fields
+// that share a name with one of derp's own attributes (such as "code") are skipped.
+// Sensitive values are removed by the current Redactor first.
+func (err Error) LogValue() slog.Value {
+
+	fields := err.GetFields()
+	severity := err.GetSeverity()
+
+	if redactor := currentRedactor(); redactor != nil {
+		err = redactor.redactError(err, false)
+		fields, _ = redactor.redactValue(fields).(map[string]any)
+	}
+
+	attributes := make([]slog.Attr, 0, 8)
+
+	if id := err.GetErrorID(); id != "" {
+		attributes = append(attributes, slog.String("id", id))
+	}
+
+	attributes = append(attributes,
+		slog.Int("code", err.Code),
+		slog.String("location", err.Location),
+		slog.String("message", err.Message),
+		slog.String("severity", severity.String()),
+	)
+
+	if err.Template != "" {
+		attributes = append(attributes, slog.String("template", err.Template))
+	}
+
+	if err.URL != "" {
+		attributes = append(attributes, slog.String("url", err.URL))
+	}
+
+	if tags := err.GetTags(); len(tags) > 0 {
+		attributes = append(attributes, slog.Any("tags", tags))
+	}
+
+	if len(err.Details) > 0 {
+		attributes = append(attributes, slog.Any("details", err.Details))
+	}
+
+	if NotNil(err.WrappedValue) {
+		attributes = append(attributes, innerErrorAttr(err.WrappedValue))
+	}
+
+	// RULE: Fields never replace derp's own attributes, just like the JSON plugin
+	reserved := make(map[string]struct{}, len(attributes))
+
+	for _, attribute := range attributes {
+		reserved[attribute.Key] = struct{}{}
+	}
+
+	// Sorted, so that log lines are stable from one error to the next
+	keys := make([]string, 0, len(fields))
+
+	for key := range fields {
+		if _, exists := reserved[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		attributes = append(attributes, slog.Any(key, fields[key]))
+	}
+
+	return slog.GroupValue(attributes...)
+}
+
+// LogValue implements the slog.LogValuer interface, so that HTTPErrors are logged
+// as structured groups.  Headers are omitted, because they rarely help and often leak.
+func (err HTTPError) LogValue() slog.Value {
+
+	if redactor := currentRedactor(); redactor != nil {
+		err = redactor.redactHTTPError(err, false)
+	}
+
+	attributes := []slog.Attr{
+		slog.Int("code", err.GetErrorCode()),
+		slog.String("message", currentRedactor().Scrub(err.Error())),
+		slog.String("severity", ErrorSeverity(err).String()),
+		slog.String("method", err.Request.Method),
+		slog.String("url", err.Request.URL),
+	}
+
+	if err.Response.Body != "" {
+		attributes = append(attributes, slog.String("responseBody", err.Response.Body))
+	}
+
+	if err.Duration > 0 {
+		attributes = append(attributes, slog.Duration("duration", err.Duration))
+	}
+
+	if NotNil(err.WrappedValue) {
+		attributes = append(attributes, innerErrorAttr(err.WrappedValue))
+	}
+
+	return slog.GroupValue(attributes...)
+}
+
+// innerErrorAttr describes a wrapped error, using its own LogValue when it has one.
+// Other errors are logged as text, scrubbed by the current Redactor.
+func innerErrorAttr(inner error) slog.Attr {
+
+	if valuer, ok := inner.(slog.LogValuer); ok {
+		return slog.Any("innerError", valuer)
+	}
+
+	return slog.String("innerError", currentRedactor().Scrub(inner.Error()))
+}
@@ -0,0 +1,138 @@
+package derp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields_Options(t *testing.T) {
+
+	err := Internal("location", "message",
+		WithField("userId", 42),
+		WithFields(map[string]any{"tenant": "acme", "shard": 3}),
+	)
+
+	require.Equal(t, map[string]any{"userId": 42, "tenant": "acme", "shard": 3}, err.Fields)
+	require.False(t, err.IsZero())
+}
+
+func TestFields_MergeAcrossChain(t *testing.T) {
+
+	inner := NotFound("inner", "message", WithField("userId", 42), WithField("table", "users"))
+	outer := Wrap(inner, "outer", "message", WithField("table", "accounts"))
+
+	// Outer fields override inner fields, and standard wrappers are looked through
+	expected := map[string]any{"userId": 42, "table": "accounts"}
+	require.Equal(t, expected, AllFields(outer))
+	require.Equal(t, expected, AllFields(fmt.Errorf("wrapped: %w", outer)))
+
+	require.Nil(t, AllFields(nil))
+	require.Nil(t, AllFields(errors.New("plain")))
+
+	// Callers may modify the result without changing the error
+	AllFields(inner)["userId"] = 0
+	require.Equal(t, 42, inner.Fields["userId"])
+}
+
+func TestFields_JSON(t *testing.T) {
+
+	inner := NotFound("inner", "message", WithField("userId", 42))
+	outer := Wrap(inner, "outer", "message", WithField("password", "hunter2"))
+
+	var result map[string]any
+	require.Nil(t, json.Unmarshal([]byte(Serialize(outer)), &result))
+
+	fields := result["fields"].(map[string]any)
+	require.Equal(t, float64(42), fields["userId"])
+	require.Equal(t, "[REDACTED]", fields["password"])
+}
+
+func TestLogValue_Error(t *testing.T) {
+
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+
+	inner := errors.New("connection refused")
+	err := Internal("location", "message", WithField("userId", 42), WithField("token", "secret"), WithWrappedValue(inner))
+	logger.Error("failed", "error", err)
+
+	var result map[string]any
+	require.Nil(t, json.Unmarshal(buffer.Bytes(), &result))
+
+	group := result["error"].(map[string]any)
+	require.Equal(t, float64(500), group["code"])
+	require.Equal(t, "location", group["location"])
+	require.Equal(t, "message", group["message"])
+	require.Equal(t, "error", group["severity"])
+	require.Equal(t, float64(42), group["userId"])
+	require.Equal(t, "[REDACTED]", group["token"])
+	require.Equal(t, "connection refused", group["innerError"])
+}
+
+func TestLogValue_FieldCollisions(t *testing.T) {
+
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+
+	err := NotFound("location", "message",
+		WithField("code", "ABC"),
+		WithField("message", "override"),
+		WithField("userId", 42),
+	)
+
+	logger.Error("failed", "error", err)
+
+	// Each key is written once, and derp's own attributes win
+	require.Equal(t, 1, bytes.Count(buffer.Bytes(), []byte(`"code":`)))
+	require.Equal(t, 1, bytes.Count(buffer.Bytes(), []byte(`"message":`)))
+
+	var result map[string]any
+	require.Nil(t, json.Unmarshal(buffer.Bytes(), &result))
+
+	logged := result["error"].(map[string]any)
+	require.Equal(t, float64(404), logged["code"])
+	require.Equal(t, "message", logged["message"])
+	require.Equal(t, float64(42), logged["userId"])
+}
+
+func TestLogValue_HTTPError(t *testing.T) {
+
+	err := HTTPError{
+		Request:  HTTPRequestReport{Method: "GET", URL: "https://example.com"},
+		Response: HTTPResponseReport{StatusCode: 404, Status: "404 Not Found"},
+	}
+
+	attributes := err.LogValue().Group()
+	values := make(map[string]slog.Value, len(attributes))
+
+	for _, attribute := range attributes {
+		values[attribute.Key] = attribute.Value
+	}
+
+	require.Equal(t, int64(404), values["code"].Int64())
+	require.Equal(t, "GET", values["method"].String())
+	require.Equal(t, "https://example.com", values["url"].String())
+	require.Equal(t, "info", values["severity"].String())
+}
+
+func TestLogValue_InnerErrorScrubbed(t *testing.T) {
+
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+
+	// Wrapped errors without a LogValue are logged as scrubbed text
+	logger.Error("failed", "error", Wrap(fmt.Errorf("dial: Bearer abc.def"), "location", "message"))
+	require.Contains(t, buffer.String(), `"innerError":"dial: [REDACTED]"`)
+
+	// ...and so are transport failures, whose message is the text of the wrapped error
+	buffer.Reset()
+	logger.Error("failed", "error", HTTPError{WrappedValue: errors.New("dial: Bearer abc.def")})
+	require.Contains(t, buffer.String(), `"message":"dial: [REDACTED]"`)
+	require.NotContains(t, buffer.String(), "abc.def")
+}
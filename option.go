@@ -1,5 +1,7 @@
 package derp
 
+import "time"
+
 // Option defines a function that modifies a derp.Error
 type Option func(*Error)
 
@@ -56,6 +58,19 @@ func WithWrappedValue(inner error) Option {
 func WithMessage(message string) Option {
 	return func(e *Error) {
 		e.Message = message
+
+		// Templated errors are re-rendered with the parameters they already have
+		if e.Template != "" {
+			e.Template = message
+			e.Message = renderTemplate(message, e.Params)
+		}
+	}
+}
+
+// WithURL returns an option that sets the URL of a web page with more information about the derp.Error
+func WithURL(url string) Option {
+	return func(e *Error) {
+		e.URL = url
 	}
 }
 
@@ -65,3 +80,81 @@ func WithLocation(location string) Option {
 		e.Location = location
 	}
 }
+
+// WithRetryable returns an option that explicitly marks whether the
+// operation that caused the derp.Error is worth retrying
+func WithRetryable(retryable bool) Option {
+	return func(e *Error) {
+		e.Retryable = &retryable
+	}
+}
+
+// WithRetryAfter returns an option that sets how long to wait
+// before retrying the operation that caused the derp.Error
+func WithRetryAfter(retryAfter time.Duration) Option {
+	return func(e *Error) {
+		e.RetryAfter = retryAfter
+	}
+}
+
+// WithSeverity returns an option that sets the derp.Error severity
+func WithSeverity(severity Severity) Option {
+	return func(e *Error) {
+		e.Severity = severity
+	}
+}
+
+// WithField returns an option that adds a single typed field to the derp.Error
+func WithField(key string, value any) Option {
+	return func(e *Error) {
+
+		if e.Fields == nil {
+			e.Fields = make(map[string]any, 1)
+		}
+
+		e.Fields[key] = value
+	}
+}
+
+// WithFields returns an option that adds several typed fields to the derp.Error
+func WithFields(fields map[string]any) Option {
+	return func(e *Error) {
+
+		if e.Fields == nil {
+			e.Fields = make(map[string]any, len(fields))
+		}
+
+		for key, value := range fields {
+			e.Fields[key] = value
+		}
+	}
+}
+
+// WithTags returns an option that adds labels (such as "billing") to the derp.Error.
+// Tags that are already present are not added twice.
+func WithTags(tags ...string) Option {
+	return func(e *Error) {
+		e.Tags = appendTags(e.Tags, tags...)
+	}
+}
+
+// WithPublicMessage returns an option that sets text which is safe to show end users
+func WithPublicMessage(message string) Option {
+	return func(e *Error) {
+		e.PublicMessage = message
+	}
+}
+
+// WithMessageKey returns an option that sets the catalog key of a localized, user-facing message
+func WithMessageKey(key string) Option {
+	return func(e *Error) {
+		e.MessageKey = key
+	}
+}
+
+// WithID returns an option that sets the unique ID of the derp.Error.  Use NewID to create one.
+func WithID(id string) Option {
+	return func(e *Error) {
+		e.ID = id
+	}
+}
@@ -3,6 +3,7 @@ package derp
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -73,6 +74,11 @@ func TestOption_WithMessage(t *testing.T) {
 	assert.Equal(t, "New Message", e.Message)
 }
 
+func TestOption_WithURL(t *testing.T) {
+	e := newError(codeNotFoundError, "Location", "Message", WithURL("https://example.com/errors/missing"))
+	assert.Equal(t, "https://example.com/errors/missing", URL(e))
+}
+
 func TestOption_WithWrappedValue(t *testing.T) {
 	e := newError(codeNotFoundError, "Location", "Message", WithWrappedValue(errors.New("wrapped error")))
 	assert.Equal(t, "wrapped error", e.WrappedValue.Error())
@@ -87,3 +93,27 @@ func TestWithMessage(t *testing.T) {
 	err := newError(123, "whatever", "dude", WithMessage("message"))
 	assert.Equal(t, "message", Message(err))
 }
+
+func TestOption_WithRetryable(t *testing.T) {
+	e := newError(codeNotFoundError, "Location", "Message", WithRetryable(true))
+	require.NotNil(t, e.Retryable)
+	assert.True(t, *e.Retryable)
+	assert.True(t, e.GetRetryable())
+	assert.False(t, e.IsZero())
+}
+
+func TestOption_WithRetryAfter(t *testing.T) {
+	e := newError(codeServiceUnavailableError, "Location", "Message", WithRetryAfter(time.Minute))
+	assert.Equal(t, time.Minute, e.GetRetryAfter())
+	assert.Equal(t, time.Minute, RetryAfter(e))
+
+	// The explicit value is visible through wrapping, too
+	assert.Equal(t, time.Minute, RetryAfter(Wrap(e, "Outer", "Message")))
+}
+
+func TestOption_WithSeverity(t *testing.T) {
+	e := newError(codeNotFoundError, "Location", "Message", WithSeverity(SeverityCritical))
+	assert.Equal(t, SeverityCritical, e.Severity)
+	assert.Equal(t, SeverityCritical, e.GetSeverity())
+	assert.False(t, e.IsZero())
+}
@@ -24,19 +24,44 @@ type Reporter interface {
 // moment with no reporters.  A Clear-then-Add sequence is also safe, but it opens a brief
 // window where errors reported by other goroutines reach an empty list and vanish.
 type ReporterList struct {
-	lock      sync.Mutex                 // serializes writers; readers never take it
-	reporters atomic.Pointer[[]Reporter] // the current, immutable list
+	lock      sync.Mutex                  // serializes writers; readers never take it
+	reporters atomic.Pointer[reporterSet] // the current, immutable list
+}
+
+// reporterSet is one immutable generation of a ReporterList: its reporters,
+// and the delivery statistics of each one (at the same index).
+type reporterSet struct {
+	reporters []Reporter
+	stats     []*reporterStats
 }
 
 // Set replaces the entire list with the provided reporters in a single atomic swap.
 func (list *ReporterList) Set(reporters ...Reporter) {
+	list.swap(reporters...)
+}
+
+// swap replaces the entire list with the provided reporters in a single atomic swap,
+// and returns the reporters that were replaced.
+func (list *ReporterList) swap(reporters ...Reporter) []Reporter {
 
 	// Cloned defensively: the caller may keep (and mutate) its own slice, and a published
 	// list must never change underneath a concurrent Report.
-	value := make([]Reporter, len(reporters))
-	copy(value, reporters)
+	value := reporterSet{
+		reporters: make([]Reporter, len(reporters)),
+		stats:     make([]*reporterStats, len(reporters)),
+	}
 
-	list.reporters.Store(&value)
+	copy(value.reporters, reporters)
+
+	for index, reporter := range reporters {
+		value.stats[index] = newReporterStats(reporter)
+	}
+
+	if previous := list.reporters.Swap(&value); previous != nil {
+		return previous.reporters
+	}
+
+	return nil
 }
 
 // Add appends a new reporter to this list.  This lets the developer configure
@@ -48,11 +73,19 @@ func (list *ReporterList) Add(reporter Reporter) {
 	list.lock.Lock()
 	defer list.lock.Unlock()
 
-	current := list.slice()
+	// Statistics are carried over, so that existing reporters keep their history.
+	current := list.set()
 
-	value := make([]Reporter, len(current), len(current)+1)
-	copy(value, current)
-	value = append(value, reporter)
+	value := reporterSet{
+		reporters: make([]Reporter, len(current.reporters), len(current.reporters)+1),
+		stats:     make([]*reporterStats, len(current.stats), len(current.stats)+1),
+	}
+
+	copy(value.reporters, current.reporters)
+	copy(value.stats, current.stats)
+
+	value.reporters = append(value.reporters, reporter)
+	value.stats = append(value.stats, newReporterStats(reporter))
 
 	list.reporters.Store(&value)
 }
@@ -61,7 +94,19 @@ func (list *ReporterList) Add(reporter Reporter) {
 // default JSON reporter from the list, in the event that you don't want to report
 // errors to the console.
 func (list *ReporterList) Clear() {
-	list.reporters.Store(&[]Reporter{})
+	list.reporters.Store(&reporterSet{})
+}
+
+// Reporters returns a copy of the reporters currently in this list, which can
+// be passed back to Set later (for instance, to restore a list after a test).
+func (list *ReporterList) Reporters() []Reporter {
+
+	current := list.slice()
+
+	result := make([]Reporter, len(current))
+	copy(result, current)
+
+	return result
 }
 
 // Len returns the number of reporters currently in this list.
@@ -73,11 +118,22 @@ func (list *ReporterList) Len() int {
 // it will never change -- but must NEVER write into it.
 func (list *ReporterList) slice() []Reporter {
 
-	if value := list.reporters.Load(); value != nil {
-		return *value
-	}
-
 	// A zero-value ReporterList is usable, and empty.  This returns an empty (not nil)
 	// slice so that the return value is always safe to range, index-check, and re-slice.
+	if reporters := list.set().reporters; reporters != nil {
+		return reporters
+	}
+
 	return []Reporter{}
 }
+
+// set returns the current, immutable generation of this list, including statistics.
+// Like slice, its contents must NEVER be written.
+func (list *ReporterList) set() *reporterSet {
+
+	if value := list.reporters.Load(); value != nil {
+		return value
+	}
+
+	return &reporterSet{}
+}
@@ -6,6 +6,12 @@ import (
 )
 
 // JSON prints errors to the system console as indented JSON.
+// derp errors apply the configured redaction rules when they are encoded,
+// so credentials in headers and details are never printed.
+//
+// Typed fields (the "fields" object of a derp error) are printed as top-level
+// attributes, so that log pipelines can index them.  A field never replaces one
+// of the error's own attributes, such as "code" or "message".
 type JSON struct{}
 
 // Report implements the `derp.Reporter` interface, which allows the JSON
@@ -13,6 +19,39 @@ type JSON struct{}
 func (JSON) Report(err error) {
 	// Per the Reporter contract, reporters swallow their own errors;
 	// a marshaling failure here simply prints an empty line.
-	bytes, _ := json.MarshalIndent(err, "", "\t")
+	bytes, _ := json.MarshalIndent(hoistFields(err), "", "\t")
 	fmt.Println(string(bytes))
 }
+
+// hoistFields returns a value that encodes like the error, with the entries of its
+// "fields" object moved to the top level.  Errors without fields are returned unchanged.
+func hoistFields(err error) any {
+
+	encoded, marshalError := json.Marshal(err)
+
+	if marshalError != nil {
+		return err
+	}
+
+	var object map[string]json.RawMessage
+
+	if json.Unmarshal(encoded, &object) != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+
+	if json.Unmarshal(object["fields"], &fields) != nil || len(fields) == 0 {
+		return err
+	}
+
+	delete(object, "fields")
+
+	for key, value := range fields {
+		if _, exists := object[key]; !exists {
+			object[key] = value
+		}
+	}
+
+	return object
+}
@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 )
@@ -11,3 +12,48 @@ import (
 func TestJSON_Report(_ *testing.T) {
 	JSON{}.Report(errors.New("something went wrong"))
 }
+
+// fieldsError mimics the JSON encoding of a derp error that carries typed fields.
+type fieldsError struct {
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+func (err fieldsError) Error() string {
+	return err.Message
+}
+
+// TestJSON_HoistFields verifies that typed fields are printed as top-level
+// attributes, without replacing the error's own attributes.
+func TestJSON_HoistFields(t *testing.T) {
+
+	err := fieldsError{
+		Message: "something went wrong",
+		Fields:  map[string]any{"userId": 42, "message": "overridden"},
+	}
+
+	result, ok := hoistFields(err).(map[string]json.RawMessage)
+
+	if !ok {
+		t.Fatal("expected fields to be hoisted")
+	}
+
+	if string(result["userId"]) != "42" {
+		t.Errorf("expected userId to be hoisted, got %s", result["userId"])
+	}
+
+	if string(result["message"]) != `"something went wrong"` {
+		t.Errorf("expected message to be preserved, got %s", result["message"])
+	}
+
+	if _, exists := result["fields"]; exists {
+		t.Error("expected fields object to be removed")
+	}
+
+	// Errors without fields are printed unchanged
+	plain := errors.New("plain")
+
+	if hoistFields(plain) != plain {
+		t.Error("expected plain errors to be unchanged")
+	}
+}
@@ -0,0 +1,232 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default limits on the number of distinct label values that Metrics tracks.
+const (
+	defaultMaxCodes     = 50
+	defaultMaxLocations = 200
+)
+
+// metricsOverflow is the label value that replaces codes and locations beyond the limits.
+const metricsOverflow = "other"
+
+// Metrics counts reported errors by error code, code class (such as "4xx"), and root
+// location, and serves the counts in the Prometheus text exposition format, so that
+// errors can be graphed without a log pipeline.  Add it to derp.Plugins, and mount it
+// (it is an http.Handler) wherever Prometheus scrapes:
+//
+//	metrics := plugins.NewMetrics()
+//	derp.Plugins.Add(metrics)
+//	http.Handle("/metrics", metrics)
+//
+// To keep the number of time series bounded, only the first MaxCodes codes and the
+// first MaxLocations locations get labels of their own; the rest are counted as "other".
+type Metrics struct {
+	MaxCodes     int // Distinct codes that are labeled individually.  If zero, 50 is used.
+	MaxLocations int // Distinct root locations that are labeled individually.  If zero, 200 is used.
+
+	lock      sync.Mutex
+	counts    map[metricsKey]uint64
+	codes     map[string]struct{}
+	locations map[string]struct{}
+}
+
+// metricsKey identifies a single time series.
+type metricsKey struct {
+	code     string
+	class    string
+	location string
+}
+
+// NewMetrics returns an empty Metrics reporter with the default limits.
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+// Report implements the `derp.Reporter` interface, which allows the Metrics
+// plugin to be called by the derp.Report() method.
+func (metrics *Metrics) Report(err error) {
+
+	code := metricsErrorCode(err)
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	if metrics.counts == nil {
+		metrics.counts = make(map[metricsKey]uint64)
+		metrics.codes = make(map[string]struct{})
+		metrics.locations = make(map[string]struct{})
+	}
+
+	key := metricsKey{
+		code:     bounded(metrics.codes, strconv.Itoa(code), limit(metrics.MaxCodes, defaultMaxCodes)),
+		class:    codeClass(metricsHTTPStatus(err, code)),
+		location: bounded(metrics.locations, metricsRootLocation(err), limit(metrics.MaxLocations, defaultMaxLocations)),
+	}
+
+	metrics.counts[key]++
+}
+
+// ServeHTTP implements the http.Handler interface, writing every count in the
+// Prometheus text exposition format.
+func (metrics *Metrics) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = writer.Write([]byte(metrics.Exposition()))
+}
+
+// Exposition returns every count in the Prometheus text exposition format.
+func (metrics *Metrics) Exposition() string {
+
+	metrics.lock.Lock()
+
+	keys := make([]metricsKey, 0, len(metrics.counts))
+	counts := make(map[metricsKey]uint64, len(metrics.counts))
+
+	for key, count := range metrics.counts {
+		keys = append(keys, key)
+		counts[key] = count
+	}
+
+	metrics.lock.Unlock()
+
+	// Sorted, so that the output is stable from one scrape to the next
+	sort.Slice(keys, func(i, j int) bool {
+
+		if keys[i].code != keys[j].code {
+			return keys[i].code < keys[j].code
+		}
+
+		return keys[i].location < keys[j].location
+	})
+
+	var result strings.Builder
+
+	result.WriteString("# HELP derp_errors_total Errors reported through derp, by code, code class, and root location.\n")
+	result.WriteString("# TYPE derp_errors_total counter\n")
+
+	for _, key := range keys {
+		fmt.Fprintf(&result, "derp_errors_total{code=\"%s\",class=\"%s\",location=\"%s\"} %d\n",
+			escapeLabel(key.code),
+			escapeLabel(key.class),
+			escapeLabel(key.location),
+			counts[key],
+		)
+	}
+
+	return result.String()
+}
+
+// Reset forgets every count, and every label value that was seen.
+func (metrics *Metrics) Reset() {
+
+	metrics.lock.Lock()
+	defer metrics.lock.Unlock()
+
+	metrics.counts = nil
+	metrics.codes = nil
+	metrics.locations = nil
+}
+
+// bounded returns the value if it has already been seen, or if there is room to remember it.
+// Otherwise it returns metricsOverflow, so that the number of label values never exceeds the limit.
+func bounded(seen map[string]struct{}, value string, limit int) string {
+
+	if _, ok := seen[value]; ok {
+		return value
+	}
+
+	if len(seen) >= limit {
+		return metricsOverflow
+	}
+
+	seen[value] = struct{}{}
+	return value
+}
+
+// limit returns the configured limit, or the default if none was configured.
+func limit(configured int, fallback int) int {
+
+	if configured > 0 {
+		return configured
+	}
+
+	return fallback
+}
+
+// codeClass returns the class of an HTTP status code (such as "4xx"), or "other"
+// for application codes outside the HTTP range.
+func codeClass(code int) string {
+
+	if code < 100 || code > 599 {
+		return metricsOverflow
+	}
+
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// escapeLabel escapes a label value for the Prometheus text exposition format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+/******************************************
+ * Error Inspection
+ *
+ * This package cannot import derp without creating an import
+ * cycle, so errors are inspected through the same method sets
+ * that derp's own accessor functions use.
+ ******************************************/
+
+// metricsErrorCode returns the code of any error, defaulting to 500 just like derp.ErrorCode.
+func metricsErrorCode(err error) int {
+
+	var getter interface{ GetErrorCode() int }
+
+	if errors.As(err, &getter) {
+		return getter.GetErrorCode()
+	}
+
+	return http.StatusInternalServerError
+}
+
+// metricsHTTPStatus returns the HTTP status that an error maps onto, just like derp.HTTPStatus,
+// so that registered application codes are counted in the class of their status.  Errors
+// that do not report one are classified by their own code.
+func metricsHTTPStatus(err error, code int) int {
+
+	var getter interface{ GetHTTPStatus() int }
+
+	if errors.As(err, &getter) {
+		return getter.GetHTTPStatus()
+	}
+
+	return code
+}
+
+// metricsRootLocation returns the deepest non-empty location in the chain, just like derp.RootLocation.
+func metricsRootLocation(err error) string {
+
+	result := ""
+
+	for err != nil {
+
+		if getter, ok := err.(interface{ GetLocation() string }); ok {
+			if location := getter.GetLocation(); location != "" {
+				result = location
+			}
+		}
+
+		err = errors.Unwrap(err)
+	}
+
+	return result
+}
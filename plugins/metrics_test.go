@@ -0,0 +1,124 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// codedError mimics a derp error, which this package cannot import.
+type codedError struct {
+	code     int
+	location string
+	inner    error
+}
+
+func (err codedError) Error() string       { return err.location }
+func (err codedError) GetErrorCode() int   { return err.code }
+func (err codedError) GetLocation() string { return err.location }
+func (err codedError) Unwrap() error       { return err.inner }
+
+// mappedError mimics a derp error with a registered application code,
+// which reports the HTTP status that its code maps onto.
+type mappedError struct {
+	codedError
+	status int
+}
+
+func (err mappedError) GetHTTPStatus() int { return err.status }
+
+func TestMetrics_Report(t *testing.T) {
+
+	metrics := NewMetrics()
+
+	inner := codedError{code: 404, location: "db.Load"}
+	metrics.Report(codedError{code: 404, location: "users.Get", inner: inner})
+	metrics.Report(fmt.Errorf("wrapped: %w", codedError{code: 404, location: "users.Get", inner: inner}))
+	metrics.Report(codedError{code: 502, location: "upstream.Call"})
+	metrics.Report(errors.New("plain"))
+
+	expected := strings.Join([]string{
+		"# HELP derp_errors_total Errors reported through derp, by code, code class, and root location.",
+		"# TYPE derp_errors_total counter",
+		`derp_errors_total{code="404",class="4xx",location="db.Load"} 2`,
+		`derp_errors_total{code="500",class="5xx",location=""} 1`,
+		`derp_errors_total{code="502",class="5xx",location="upstream.Call"} 1`,
+		"",
+	}, "\n")
+
+	if actual := metrics.Exposition(); actual != expected {
+		t.Errorf("unexpected exposition:\n%s", actual)
+	}
+}
+
+func TestMetrics_MappedStatus(t *testing.T) {
+
+	metrics := NewMetrics()
+	metrics.Report(mappedError{codedError: codedError{code: 10042, location: "billing.Charge"}, status: 402})
+	metrics.Report(codedError{code: 30001, location: "billing.Charge"})
+
+	exposition := metrics.Exposition()
+
+	// Registered application codes are counted in the class of their HTTP status
+	if !strings.Contains(exposition, `derp_errors_total{code="10042",class="4xx",location="billing.Charge"} 1`) {
+		t.Errorf("expected the mapped class:\n%s", exposition)
+	}
+
+	if !strings.Contains(exposition, `derp_errors_total{code="30001",class="other",location="billing.Charge"} 1`) {
+		t.Errorf("expected unmapped codes to be other:\n%s", exposition)
+	}
+}
+
+func TestMetrics_Cardinality(t *testing.T) {
+
+	metrics := &Metrics{MaxCodes: 2, MaxLocations: 2}
+
+	for index := 0; index < 10; index++ {
+		metrics.Report(codedError{code: 1000 + index, location: fmt.Sprintf("location.%d", index)})
+	}
+
+	exposition := metrics.Exposition()
+
+	if lines := strings.Count(exposition, "derp_errors_total{"); lines != 3 {
+		t.Errorf("expected 3 time series, got %d:\n%s", lines, exposition)
+	}
+
+	if !strings.Contains(exposition, `derp_errors_total{code="other",class="other",location="other"} 8`) {
+		t.Errorf("expected overflow to be counted as other:\n%s", exposition)
+	}
+
+	metrics.Reset()
+
+	if strings.Contains(metrics.Exposition(), "derp_errors_total{") {
+		t.Error("expected Reset to forget every count")
+	}
+}
+
+func TestMetrics_Escaping(t *testing.T) {
+
+	metrics := NewMetrics()
+	metrics.Report(codedError{code: 400, location: "say \"hi\"\\\n"})
+
+	if !strings.Contains(metrics.Exposition(), `location="say \"hi\"\\\n"`) {
+		t.Errorf("expected escaped label:\n%s", metrics.Exposition())
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+
+	metrics := NewMetrics()
+	metrics.Report(codedError{code: 404, location: "users.Get"})
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type: %s", contentType)
+	}
+
+	if !strings.Contains(recorder.Body.String(), `derp_errors_total{code="404",class="4xx",location="users.Get"} 1`) {
+		t.Errorf("unexpected body:\n%s", recorder.Body.String())
+	}
+}
@@ -1,3 +1,32 @@
 // Package plugins provides a number of reporting plugins for derp.
 // Plugins can be activated by calling derp.Plugins.Add() with the desired plugin.
 package plugins
+
+import "sync/atomic"
+
+// scrubber holds the function that removes sensitive values from plain error text.
+var scrubber atomic.Pointer[func(string) string]
+
+// SetScrubber sets the function that removes sensitive values (such as credentials) from
+// the plain text of errors, wherever a plugin writes error text that derp did not format.
+// derp installs its current Redactor (see derp.SetRedactor) when it starts, so most programs
+// never call this.  Passing nil writes plain text unchanged.
+func SetScrubber(scrub func(string) string) {
+
+	if scrub == nil {
+		scrubber.Store(nil)
+		return
+	}
+
+	scrubber.Store(&scrub)
+}
+
+// scrub removes sensitive values from plain error text, using the function set by SetScrubber.
+func scrub(text string) string {
+
+	if scrub := scrubber.Load(); scrub != nil {
+		return (*scrub)(text)
+	}
+
+	return text
+}
@@ -0,0 +1,364 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRingCapacity is the number of errors that a zero-value Ring keeps.
+const defaultRingCapacity = 100
+
+// defaultRingGroups is the number of fingerprints that a Ring counts by default.
+const defaultRingGroups = 1000
+
+// Ring keeps the most recent errors in memory, along with counts of every error grouped by
+// fingerprint, so that operators can see what is failing on a server without shell access.
+// It is also an http.Handler that serves these errors as JSON, or as a simple HTML page:
+//
+//	ring := plugins.NewRing(100)
+//	derp.Plugins.Add(ring)
+//	adminMux.Handle("/errors", ring)
+//
+// Both formats can be filtered with the "code" query parameter (such as "404", or "4xx"
+// for a whole class), and the "location" query parameter (which matches any part of the
+// location).  A zero-value Ring is ready to use, and keeps the most recent 100 errors.
+// Mount the handler on an internal or authenticated route, because errors
+// describe the inner workings of your application.
+type Ring struct {
+	MaxGroups int // Distinct fingerprints that are counted.  The least recently seen are forgotten first.  If zero, 1000 is used.
+
+	lock    sync.Mutex
+	entries []RingEntry           // circular buffer of recent errors
+	next    int                   // index where the next entry is written
+	size    int                   // number of entries written, up to len(entries)
+	groups  map[string]*RingGroup // counts of every error, by fingerprint
+}
+
+// RingEntry describes one error kept by a Ring.
+type RingEntry struct {
+	Time        time.Time       `json:"time"`            // When the error was created (or reported, if it has no timestamp)
+	Fingerprint string          `json:"fingerprint"`     // Identifier that groups similar errors together
+	Code        int             `json:"code"`            // Error code, defaulting to 500 just like derp.ErrorCode
+	HTTPStatus  int             `json:"httpStatus"`      // HTTP status that the code maps onto, just like derp.HTTPStatus
+	Location    string          `json:"location"`        // Location of the outermost error
+	Message     string          `json:"message"`         // Message of the outermost error
+	Error       json.RawMessage `json:"error,omitempty"` // Complete JSON encoding of the error, as redacted by derp
+}
+
+// RingGroup counts every error with the same fingerprint.
+type RingGroup struct {
+	Fingerprint string    `json:"fingerprint"` // Identifier shared by every error in the group
+	Count       int       `json:"count"`       // Number of errors reported with this fingerprint
+	Code        int       `json:"code"`        // Code of the most recent error in the group
+	HTTPStatus  int       `json:"httpStatus"`  // HTTP status of the most recent error in the group
+	Location    string    `json:"location"`    // Location of the most recent error in the group
+	Message     string    `json:"message"`     // Message of the most recent error in the group
+	FirstSeen   time.Time `json:"firstSeen"`   // When the first error in the group was reported
+	LastSeen    time.Time `json:"lastSeen"`    // When the most recent error in the group was reported
+}
+
+// NewRing returns a Ring that keeps the most recent `capacity` errors.
+func NewRing(capacity int) *Ring {
+
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &Ring{
+		entries: make([]RingEntry, capacity),
+		groups:  make(map[string]*RingGroup),
+	}
+}
+
+// Report implements the `derp.Reporter` interface, which allows the Ring
+// plugin to be called by the derp.Report() method.
+func (ring *Ring) Report(err error) {
+
+	entry := newRingEntry(err)
+
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if ring.entries == nil {
+		ring.entries = make([]RingEntry, defaultRingCapacity)
+	}
+
+	if ring.groups == nil {
+		ring.groups = make(map[string]*RingGroup)
+	}
+
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % len(ring.entries)
+
+	if ring.size < len(ring.entries) {
+		ring.size++
+	}
+
+	group, ok := ring.groups[entry.Fingerprint]
+
+	if !ok {
+		ring.evictGroup()
+		group = &RingGroup{Fingerprint: entry.Fingerprint, FirstSeen: entry.Time}
+		ring.groups[entry.Fingerprint] = group
+	}
+
+	group.Count++
+	group.Code = entry.Code
+	group.HTTPStatus = entry.HTTPStatus
+	group.Location = entry.Location
+	group.Message = entry.Message
+	group.LastSeen = entry.Time
+}
+
+// Entries returns the errors kept by the Ring, newest first.
+func (ring *Ring) Entries() []RingEntry {
+
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	result := make([]RingEntry, 0, ring.size)
+
+	for offset := 1; offset <= ring.size; offset++ {
+		index := (ring.next - offset + len(ring.entries)) % len(ring.entries)
+		result = append(result, ring.entries[index])
+	}
+
+	return result
+}
+
+// Groups returns the counts of every error by fingerprint, most frequent first.
+func (ring *Ring) Groups() []RingGroup {
+
+	ring.lock.Lock()
+	result := make([]RingGroup, 0, len(ring.groups))
+
+	for _, group := range ring.groups {
+		result = append(result, *group)
+	}
+
+	ring.lock.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+
+	return result
+}
+
+// evictGroup forgets the least recently seen group, if the Ring is counting too many.
+func (ring *Ring) evictGroup() {
+
+	if len(ring.groups) < limit(ring.MaxGroups, defaultRingGroups) {
+		return
+	}
+
+	oldest := ""
+
+	for fingerprint, group := range ring.groups {
+		if oldest == "" || group.LastSeen.Before(ring.groups[oldest].LastSeen) {
+			oldest = fingerprint
+		}
+	}
+
+	delete(ring.groups, oldest)
+}
+
+/******************************************
+ * HTTP Handler
+ ******************************************/
+
+// ringResponse is the JSON document served by a Ring.
+type ringResponse struct {
+	Errors []RingEntry `json:"errors"`
+	Groups []RingGroup `json:"groups"`
+}
+
+// ServeHTTP implements the http.Handler interface.  It serves JSON when the request asks for
+// it (with "?format=json", or an Accept header of "application/json"), and HTML otherwise.
+func (ring *Ring) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+
+	filter := newRingFilter(request)
+
+	response := ringResponse{
+		Errors: make([]RingEntry, 0),
+		Groups: make([]RingGroup, 0),
+	}
+
+	for _, entry := range ring.Entries() {
+		if filter.matches(entry.Code, entry.HTTPStatus, entry.Location) {
+			response.Errors = append(response.Errors, entry)
+		}
+	}
+
+	for _, group := range ring.Groups() {
+		if filter.matches(group.Code, group.HTTPStatus, group.Location) {
+			response.Groups = append(response.Groups, group)
+		}
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+
+	if wantsJSON(request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(response)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	_ = ringTemplate.Execute(writer, map[string]any{
+		"Code":     filter.code,
+		"Location": filter.location,
+		"Errors":   response.Errors,
+		"Groups":   response.Groups,
+	})
+}
+
+// ringFilter selects errors by code (or code class) and location.
+type ringFilter struct {
+	code     string
+	location string
+}
+
+// newRingFilter reads a filter from the query parameters of a request.
+func newRingFilter(request *http.Request) ringFilter {
+
+	query := request.URL.Query()
+
+	return ringFilter{
+		code:     strings.TrimSpace(query.Get("code")),
+		location: strings.TrimSpace(query.Get("location")),
+	}
+}
+
+// matches returns TRUE if an error with this code and location passes the filter.
+// Classes (such as "4xx") are matched by HTTP status, so that they include registered
+// application codes.
+func (filter ringFilter) matches(code int, httpStatus int, location string) bool {
+
+	if filter.location != "" && !strings.Contains(location, filter.location) {
+		return false
+	}
+
+	switch {
+
+	case filter.code == "":
+		return true
+
+	case strings.HasSuffix(strings.ToLower(filter.code), "xx"):
+		return codeClass(httpStatus) == strings.ToLower(filter.code)
+	}
+
+	return strconv.Itoa(code) == filter.code
+}
+
+// wantsJSON returns TRUE if the request asks for JSON instead of HTML.
+func wantsJSON(request *http.Request) bool {
+
+	if request.URL.Query().Get("format") == "json" {
+		return true
+	}
+
+	return strings.Contains(request.Header.Get("Accept"), "application/json")
+}
+
+// ringTemplate is the HTML page served by a Ring.
+var ringTemplate = template.Must(template.New("ring").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Recent Errors</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Recent Errors</h1>
+<form method="get">
+<label>Code <input name="code" value="{{.Code}}" placeholder="404 or 5xx"></label>
+<label>Location <input name="location" value="{{.Location}}"></label>
+<button type="submit">Filter</button>
+</form>
+<h2>By Fingerprint</h2>
+<table>
+<tr><th>Count</th><th>Code</th><th>Location</th><th>Message</th><th>Last Seen</th><th>Fingerprint</th></tr>
+{{range .Groups}}<tr><td>{{.Count}}</td><td>{{.Code}}</td><td>{{.Location}}</td><td>{{.Message}}</td><td>{{.LastSeen.Format "2006-01-02 15:04:05"}}</td><td>{{.Fingerprint}}</td></tr>
+{{else}}<tr><td colspan="6">No errors</td></tr>
+{{end}}</table>
+<h2>Most Recent</h2>
+<table>
+<tr><th>Time</th><th>Code</th><th>Location</th><th>Message</th></tr>
+{{range .Errors}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Code}}</td><td>{{.Location}}</td><td>{{.Message}}</td></tr>
+{{else}}<tr><td colspan="4">No errors</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+/******************************************
+ * Error Inspection
+ ******************************************/
+
+// newRingEntry describes an error for a Ring.
+func newRingEntry(err error) RingEntry {
+
+	code := metricsErrorCode(err)
+
+	result := RingEntry{
+		Time:        time.Now(),
+		Fingerprint: ringFingerprint(err),
+		Code:        code,
+		HTTPStatus:  metricsHTTPStatus(err, code),
+		Message:     scrub(err.Error()),
+	}
+
+	if getter, ok := err.(interface{ GetTimeStamp() time.Time }); ok && !getter.GetTimeStamp().IsZero() {
+		result.Time = getter.GetTimeStamp()
+	}
+
+	if getter, ok := err.(interface{ GetLocation() string }); ok {
+		result.Location = getter.GetLocation()
+	}
+
+	if getter, ok := err.(interface{ GetMessage() string }); ok {
+		result.Message = getter.GetMessage()
+	}
+
+	if encoded, marshalError := json.Marshal(err); marshalError == nil && string(encoded) != "{}" {
+		result.Error = encoded
+	}
+
+	return result
+}
+
+// ringFingerprint returns the fingerprint of any error, using derp's own fingerprint
+// when available.  Other errors are grouped by their code, type, and message.
+func ringFingerprint(err error) string {
+
+	var getter interface{ GetFingerprint() string }
+
+	if errors.As(err, &getter) {
+		return getter.GetFingerprint()
+	}
+
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%d\x00%T\x00%s", metricsErrorCode(err), err, err.Error())
+
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
@@ -0,0 +1,180 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRing_Entries(t *testing.T) {
+
+	ring := NewRing(3)
+
+	if len(ring.Entries()) != 0 {
+		t.Fatal("expected an empty ring")
+	}
+
+	for _, location := range []string{"first", "second", "third", "fourth"} {
+		ring.Report(codedError{code: 404, location: location})
+	}
+
+	entries := ring.Entries()
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	// Newest first, and the oldest has been overwritten
+	for index, expected := range []string{"fourth", "third", "second"} {
+		if entries[index].Location != expected {
+			t.Errorf("entry %d: expected %s, got %s", index, expected, entries[index].Location)
+		}
+	}
+}
+
+func TestRing_ZeroValue(t *testing.T) {
+
+	ring := &Ring{}
+
+	if len(ring.Entries()) != 0 || len(ring.Groups()) != 0 {
+		t.Fatal("expected an empty ring")
+	}
+
+	for index := 0; index < defaultRingCapacity+1; index++ {
+		ring.Report(codedError{code: 404, location: "zero"})
+	}
+
+	if entries := ring.Entries(); len(entries) != defaultRingCapacity {
+		t.Errorf("expected %d entries, got %d", defaultRingCapacity, len(entries))
+	}
+
+	if groups := ring.Groups(); len(groups) != 1 || groups[0].Count != defaultRingCapacity+1 {
+		t.Errorf("expected one group counting every error, got %+v", groups)
+	}
+}
+
+func TestRing_Groups(t *testing.T) {
+
+	ring := NewRing(2)
+
+	ring.Report(codedError{code: 404, location: "users.Get"})
+	ring.Report(codedError{code: 404, location: "users.Get"})
+	ring.Report(codedError{code: 404, location: "users.Get"})
+	ring.Report(errors.New("plain"))
+
+	groups := ring.Groups()
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	// Counts survive even after the entries themselves are overwritten
+	if groups[0].Count != 3 || groups[0].Location != "users.Get" {
+		t.Errorf("unexpected first group: %+v", groups[0])
+	}
+
+	if groups[1].Count != 1 || groups[1].Code != 500 || groups[1].Message != "plain" {
+		t.Errorf("unexpected second group: %+v", groups[1])
+	}
+}
+
+func TestRing_MaxGroups(t *testing.T) {
+
+	ring := NewRing(10)
+	ring.MaxGroups = 2
+
+	ring.Report(errors.New("first"))
+	ring.Report(errors.New("second"))
+	ring.Report(errors.New("third"))
+
+	groups := ring.Groups()
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+
+	for _, group := range groups {
+		if group.Message == "first" {
+			t.Error("expected the least recently seen group to be forgotten")
+		}
+	}
+}
+
+func TestRing_ServeJSON(t *testing.T) {
+
+	ring := NewRing(10)
+	ring.Report(codedError{code: 404, location: "users.Get"})
+	ring.Report(codedError{code: 502, location: "upstream.Call"})
+	ring.Report(codedError{code: 503, location: "users.Save"})
+	ring.Report(mappedError{codedError: codedError{code: 10042, location: "billing.Charge"}, status: 402})
+
+	filtered := func(query string) ringResponse {
+
+		recorder := httptest.NewRecorder()
+		ring.ServeHTTP(recorder, httptest.NewRequest("GET", "/errors?format=json&"+query, nil))
+
+		var result ringResponse
+
+		if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+			t.Fatalf("unable to decode response: %s", err)
+		}
+
+		return result
+	}
+
+	if result := filtered(""); len(result.Errors) != 4 || len(result.Groups) != 4 {
+		t.Errorf("expected every error, got %+v", result)
+	}
+
+	if result := filtered("code=404"); len(result.Errors) != 1 || result.Errors[0].Location != "users.Get" {
+		t.Errorf("expected the 404 only, got %+v", result)
+	}
+
+	if result := filtered("code=5xx"); len(result.Errors) != 2 {
+		t.Errorf("expected both 5xx errors, got %+v", result)
+	}
+
+	if result := filtered("code=5XX&location=users"); len(result.Errors) != 1 || result.Errors[0].Code != 503 {
+		t.Errorf("expected the 503 only, got %+v", result)
+	}
+
+	// Registered application codes are matched by the class of their HTTP status
+	if result := filtered("code=4xx"); len(result.Errors) != 2 || len(result.Groups) != 2 || result.Errors[0].HTTPStatus != 402 {
+		t.Errorf("expected the 404 and the mapped 402, got %+v", result)
+	}
+}
+
+func TestRing_ServeHTML(t *testing.T) {
+
+	ring := NewRing(10)
+	ring.Report(codedError{code: 404, location: "<script>alert(1)</script>"})
+
+	recorder := httptest.NewRecorder()
+	ring.ServeHTTP(recorder, httptest.NewRequest("GET", "/errors?code=404", nil))
+
+	body := recorder.Body.String()
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("unexpected content type: %s", recorder.Header().Get("Content-Type"))
+	}
+
+	if !strings.Contains(body, `value="404"`) {
+		t.Error("expected the filter to be shown in the form")
+	}
+
+	if strings.Contains(body, "<script>") || !strings.Contains(body, "&lt;script&gt;") {
+		t.Error("expected locations to be escaped")
+	}
+
+	// Accept headers also select JSON
+	request := httptest.NewRequest("GET", "/errors", nil)
+	request.Header.Set("Accept", "application/json")
+	recorder = httptest.NewRecorder()
+	ring.ServeHTTP(recorder, request)
+
+	if recorder.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("expected JSON, got %s", recorder.Header().Get("Content-Type"))
+	}
+}
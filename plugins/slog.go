@@ -0,0 +1,76 @@
+package plugins
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Slog reports errors to a structured logger from the log/slog package.  Errors that
+// implement slog.LogValuer (including every derp error) are logged with their attributes
+// at the top level of the record, and at the level that matches their severity.  Other
+// errors are logged as plain text, scrubbed by derp's redaction rules (see SetScrubber).
+type Slog struct {
+	Logger *slog.Logger // Logger that receives each error.  If nil, slog.Default() is used.
+}
+
+// Report implements the `derp.Reporter` interface, which allows the Slog
+// plugin to be called by the derp.Report() method.
+func (reporter Slog) Report(err error) {
+
+	logger := reporter.Logger
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	message := scrub(err.Error())
+	level := slog.LevelError
+
+	valuer, ok := err.(slog.LogValuer)
+
+	// Errors without structure are logged as plain text
+	if !ok {
+		logger.LogAttrs(context.Background(), level, message)
+		return
+	}
+
+	value := valuer.LogValue().Resolve()
+
+	if value.Kind() != slog.KindGroup {
+		logger.LogAttrs(context.Background(), level, message, slog.Any("error", value))
+		return
+	}
+
+	attributes := value.Group()
+
+	for _, attribute := range attributes {
+
+		switch attribute.Key {
+
+		case "message":
+			message = attribute.Value.String()
+
+		case "severity":
+			level = severityLevel(attribute.Value.String())
+		}
+	}
+
+	logger.LogAttrs(context.Background(), level, message, attributes...)
+}
+
+// severityLevel maps a derp severity name onto the closest slog level.
+func severityLevel(severity string) slog.Level {
+
+	switch severity {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "warning":
+		return slog.LevelWarn
+	case "critical":
+		return slog.LevelError + 4
+	}
+
+	return slog.LevelError
+}
@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// valuerError mimics the LogValue of a derp error.
+type valuerError struct {
+	severity string
+}
+
+func (err valuerError) Error() string {
+	return "fallback message"
+}
+
+func (err valuerError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("code", 404),
+		slog.String("message", "record not found"),
+		slog.String("severity", err.severity),
+		slog.Int("userId", 42),
+	)
+}
+
+// slogRecord reports an error through a Slog plugin and returns the decoded log line.
+func slogRecord(t *testing.T, err error) map[string]any {
+
+	var buffer bytes.Buffer
+	handler := slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug})
+	Slog{Logger: slog.New(handler)}.Report(err)
+
+	var result map[string]any
+
+	if unmarshalError := json.Unmarshal(buffer.Bytes(), &result); unmarshalError != nil {
+		t.Fatalf("unable to decode log line: %s", unmarshalError)
+	}
+
+	return result
+}
+
+func TestSlog_LogValuer(t *testing.T) {
+
+	result := slogRecord(t, valuerError{severity: "info"})
+
+	if result["msg"] != "record not found" {
+		t.Errorf("expected message from LogValue, got %v", result["msg"])
+	}
+
+	if result["level"] != "INFO" {
+		t.Errorf("expected level from severity, got %v", result["level"])
+	}
+
+	// Attributes are flattened to the top level, so that pipelines can index them
+	if result["userId"] != float64(42) {
+		t.Errorf("expected top-level userId, got %v", result["userId"])
+	}
+}
+
+func TestSlog_Levels(t *testing.T) {
+
+	expected := map[string]string{
+		"debug":    "DEBUG",
+		"warning":  "WARN",
+		"error":    "ERROR",
+		"critical": "ERROR+4",
+		"":         "ERROR",
+	}
+
+	for severity, level := range expected {
+		if result := slogRecord(t, valuerError{severity: severity}); result["level"] != level {
+			t.Errorf("severity %q: expected %s, got %v", severity, level, result["level"])
+		}
+	}
+}
+
+func TestSlog_PlainError(t *testing.T) {
+
+	result := slogRecord(t, errors.New("something went wrong"))
+
+	if result["msg"] != "something went wrong" || result["level"] != "ERROR" {
+		t.Errorf("unexpected log line: %v", result)
+	}
+}
+
+func TestSlog_PlainErrorScrubbed(t *testing.T) {
+
+	SetScrubber(func(text string) string {
+		return strings.ReplaceAll(text, "abc.def", "[REDACTED]")
+	})
+
+	t.Cleanup(func() { SetScrubber(nil) })
+
+	result := slogRecord(t, errors.New("dial: Bearer abc.def"))
+
+	if result["msg"] != "dial: Bearer [REDACTED]" {
+		t.Errorf("expected scrubbed message, got %v", result["msg"])
+	}
+}
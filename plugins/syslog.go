@@ -0,0 +1,391 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facilities (RFC 5424, section 6.2.1) that are commonly used by applications.
+const (
+	SyslogFacilityUser   = 1
+	SyslogFacilityDaemon = 3
+	SyslogFacilityLocal0 = 16
+	SyslogFacilityLocal7 = 23
+)
+
+// Syslog severities (RFC 5424, section 6.2.1) used by the Syslog reporter.
+const (
+	syslogSeverityCritical = 2
+	syslogSeverityError    = 3
+	syslogSeverityWarning  = 4
+	syslogSeverityNotice   = 5
+	syslogSeverityInfo     = 6
+	syslogSeverityDebug    = 7
+)
+
+// syslogSeverities maps derp severity names onto syslog severities.
+var syslogSeverities = map[string]int{
+	"debug":    syslogSeverityDebug,
+	"info":     syslogSeverityInfo,
+	"warning":  syslogSeverityWarning,
+	"error":    syslogSeverityError,
+	"critical": syslogSeverityCritical,
+}
+
+// syslogStructuredDataID identifies the structured data element that carries derp values.
+// 32473 is the private enterprise number reserved for documentation (RFC 5612).
+const syslogStructuredDataID = "derp@32473"
+
+// defaultSyslogTimeout bounds each connection attempt and write.
+const defaultSyslogTimeout = 5 * time.Second
+
+// syslogSockets are the local sockets that are tried when no address is configured.
+// journald listens on /dev/log on Linux; macOS uses /var/run/syslog.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Syslog reports errors as RFC 5424 syslog messages, with a structured data element that
+// carries the error code, location, and details.  The severity of each message is the derp
+// severity of the error (read from its LogValue, as Slog does).  Errors without one are
+// classified by their code: server errors are "error", client errors are "warning", missing
+// resources are "notice", and anything else is "info".
+//
+// Messages are sent over a unix socket, UDP, or TCP (with RFC 6587 octet counting).  The
+// connection is opened on first use, and reopened after a failed write.  Syslog is safe for
+// concurrent use.
+type Syslog struct {
+	Network  string        // "unixgram", "unix", "udp", or "tcp".  If empty, the local syslog socket is used.
+	Address  string        // Address of the syslog server, or path of the unix socket
+	Facility int           // Syslog facility, such as SyslogFacilityLocal0.  If zero, SyslogFacilityUser is used.
+	AppName  string        // Application name in each message.  If empty, the name of the executable is used.
+	Hostname string        // Host name in each message.  If empty, os.Hostname() is used.
+	Timeout  time.Duration // Limit for each connection attempt and write.  If zero, 5 seconds is used.
+
+	lock       sync.Mutex
+	connection net.Conn
+	closed     bool // set by Close, after which errors are dropped
+}
+
+// NewSyslog returns a Syslog reporter that writes to the provided network and address,
+// using the "user" facility.  Use NewSyslog("", "") for the local syslog socket.
+func NewSyslog(network string, address string) *Syslog {
+	return &Syslog{
+		Network:  network,
+		Address:  address,
+		Facility: SyslogFacilityUser,
+	}
+}
+
+// Report implements the `derp.Reporter` interface, which allows the Syslog
+// plugin to be called by the derp.Report() method.
+func (reporter *Syslog) Report(err error) {
+
+	message := reporter.Format(err, time.Now())
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	// RULE: Never reopen a connection after Close, because nobody would close it again.
+	// Late deliveries (such as those from a derp.TimeoutReporter) can still arrive.
+	if reporter.closed {
+		return
+	}
+
+	// Per the Reporter contract, reporters swallow their own errors.  A failed write
+	// is retried once on a new connection, in case the server restarted.
+	for attempt := 0; attempt < 2; attempt++ {
+
+		if reporter.write(message) == nil {
+			return
+		}
+
+		reporter.closeConnection()
+	}
+}
+
+// Close closes the connection to the syslog server, if one is open.
+// Errors reported after Close are dropped.
+func (reporter *Syslog) Close() error {
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	reporter.closed = true
+	return reporter.closeConnection()
+}
+
+// Format returns the RFC 5424 message for an error (without transport framing).  The error's
+// own timestamp is used when it has one; otherwise the message is stamped with `now`.
+func (reporter *Syslog) Format(err error, now time.Time) []byte {
+
+	if getter, ok := err.(interface{ GetTimeStamp() time.Time }); ok && !getter.GetTimeStamp().IsZero() {
+		now = getter.GetTimeStamp()
+	}
+
+	code := metricsErrorCode(err)
+
+	var result bytes.Buffer
+
+	// HEADER: <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	fmt.Fprintf(&result, "<%d>1 %s %s %s %d %s ",
+		reporter.facility()*8+syslogErrorSeverity(err, code),
+		now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(reporter.hostname(), 255),
+		syslogHeaderField(reporter.appName(), 48),
+		os.Getpid(),
+		syslogHeaderField(ringFingerprint(err), 32),
+	)
+
+	// STRUCTURED-DATA
+	result.WriteString("[" + syslogStructuredDataID)
+	writeSyslogParam(&result, "code", strconv.Itoa(code))
+
+	if getter, ok := err.(interface{ GetLocation() string }); ok && getter.GetLocation() != "" {
+		writeSyslogParam(&result, "location", getter.GetLocation())
+	}
+
+	if getter, ok := err.(interface{ GetErrorID() string }); ok && getter.GetErrorID() != "" {
+		writeSyslogParam(&result, "id", getter.GetErrorID())
+	}
+
+	if getter, ok := err.(interface{ GetDetails() []any }); ok {
+		for _, detail := range getter.GetDetails() {
+			writeSyslogParam(&result, "detail", syslogDetail(detail))
+		}
+	}
+
+	result.WriteString("] ")
+
+	// MSG
+	message := scrub(err.Error())
+
+	if getter, ok := err.(interface{ GetMessage() string }); ok {
+		message = getter.GetMessage()
+	}
+
+	result.WriteString(message)
+
+	return result.Bytes()
+}
+
+// write sends one message, opening a connection if necessary.
+func (reporter *Syslog) write(message []byte) error {
+
+	if reporter.connection == nil {
+
+		connection, err := reporter.dial()
+
+		if err != nil {
+			return err
+		}
+
+		reporter.connection = connection
+	}
+
+	_ = reporter.connection.SetWriteDeadline(time.Now().Add(reporter.timeout()))
+
+	_, err := reporter.connection.Write(syslogFrame(reporter.Network, message))
+	return err
+}
+
+// dial opens a connection to the configured server, or to the first local socket that answers.
+func (reporter *Syslog) dial() (net.Conn, error) {
+
+	if reporter.Network != "" {
+		return net.DialTimeout(reporter.Network, reporter.Address, reporter.timeout())
+	}
+
+	var lastError error
+
+	for _, path := range syslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+
+			connection, err := net.DialTimeout(network, path, reporter.timeout())
+
+			if err == nil {
+				reporter.Network = network
+				reporter.Address = path
+				return connection, nil
+			}
+
+			lastError = err
+		}
+	}
+
+	return nil, lastError
+}
+
+// closeConnection closes the current connection, if any.  The caller must hold the lock.
+func (reporter *Syslog) closeConnection() error {
+
+	if reporter.connection == nil {
+		return nil
+	}
+
+	err := reporter.connection.Close()
+	reporter.connection = nil
+
+	return err
+}
+
+// timeout returns the configured timeout, or the default.
+func (reporter *Syslog) timeout() time.Duration {
+
+	if reporter.Timeout > 0 {
+		return reporter.Timeout
+	}
+
+	return defaultSyslogTimeout
+}
+
+// facility returns the configured facility, or the "user" facility if none is set.
+func (reporter *Syslog) facility() int {
+
+	// Facility 0 is reserved for kernel messages, so the zero value means "user"
+	if reporter.Facility != 0 {
+		return reporter.Facility
+	}
+
+	return SyslogFacilityUser
+}
+
+// hostname returns the configured host name, or the name of this host.
+func (reporter *Syslog) hostname() string {
+
+	if reporter.Hostname != "" {
+		return reporter.Hostname
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+
+	return "-"
+}
+
+// appName returns the configured application name, or the name of the executable.
+func (reporter *Syslog) appName() string {
+
+	if reporter.AppName != "" {
+		return reporter.AppName
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+// syslogFrame applies the framing required by a transport.  Stream transports need
+// a way to find the end of each message: TCP uses octet counting (RFC 6587), and
+// unix stream sockets use a trailing newline, which local syslog daemons expect.
+func syslogFrame(network string, message []byte) []byte {
+
+	switch network {
+
+	case "tcp", "tcp4", "tcp6":
+		return append([]byte(strconv.Itoa(len(message))+" "), message...)
+
+	case "unix":
+		return append(message, '\n')
+	}
+
+	return message
+}
+
+// syslogErrorSeverity returns the syslog severity of an error.  Errors that implement
+// slog.LogValuer (including every derp error) use their "severity" attribute, as Slog
+// does, and other errors are classified by their code.
+func syslogErrorSeverity(err error, code int) int {
+
+	if valuer, ok := err.(slog.LogValuer); ok {
+
+		if value := valuer.LogValue().Resolve(); value.Kind() == slog.KindGroup {
+			for _, attribute := range value.Group() {
+				if attribute.Key == "severity" {
+					if severity, ok := syslogSeverities[attribute.Value.String()]; ok {
+						return severity
+					}
+				}
+			}
+		}
+	}
+
+	return syslogSeverity(code)
+}
+
+// syslogSeverity derives a syslog severity from an error code.
+func syslogSeverity(code int) int {
+
+	switch {
+
+	case code == 404, code == 410:
+		return syslogSeverityNotice
+
+	case code >= 400 && code < 500:
+		return syslogSeverityWarning
+
+	case code >= 100 && code < 400:
+		return syslogSeverityInfo
+	}
+
+	return syslogSeverityError
+}
+
+// syslogHeaderField converts a value into a valid header field: printable ASCII
+// without spaces, limited in length, or "-" (the NILVALUE) if nothing is left.
+func syslogHeaderField(value string, maxLength int) string {
+
+	result := strings.Map(func(r rune) rune {
+
+		if r < 33 || r > 126 {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if len(result) > maxLength {
+		result = result[:maxLength]
+	}
+
+	if result == "" {
+		return "-"
+	}
+
+	return result
+}
+
+// writeSyslogParam writes one structured data parameter, escaping its value.
+func writeSyslogParam(buffer *bytes.Buffer, name string, value string) {
+
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+	fmt.Fprintf(buffer, ` %s="%s"`, name, value)
+}
+
+// syslogDetail converts a single derp detail into a string.
+func syslogDetail(detail any) string {
+
+	switch typed := detail.(type) {
+
+	case string:
+		return typed
+
+	case error:
+		return scrub(typed.Error())
+
+	case fmt.Stringer:
+		return typed.String()
+	}
+
+	if encoded, err := json.Marshal(detail); err == nil {
+		return string(encoded)
+	}
+
+	return fmt.Sprint(detail)
+}
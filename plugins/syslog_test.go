@@ -0,0 +1,307 @@
+package plugins
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// detailedError mimics a derp error with details, which this package cannot import.
+type detailedError struct {
+	codedError
+	details []any
+}
+
+func (err detailedError) GetDetails() []any  { return err.details }
+func (err detailedError) GetMessage() string { return "user not found" }
+
+// severeError mimics a derp error with a severity, which is read from its LogValue.
+type severeError struct {
+	codedError
+	severity string
+}
+
+func (err severeError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("code", err.code),
+		slog.String("severity", err.severity),
+	)
+}
+
+// testSyslog returns a Syslog reporter with fixed header values.
+func testSyslog(network string, address string) *Syslog {
+
+	result := NewSyslog(network, address)
+	result.Hostname = "host"
+	result.AppName = "app"
+	result.Timeout = time.Second
+
+	return result
+}
+
+func TestSyslog_Format(t *testing.T) {
+
+	err := detailedError{
+		codedError: codedError{code: 404, location: "users.Get"},
+		details:    []any{"alice", map[string]int{"id": 1}, `quote " bracket ] slash \`},
+	}
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	message := string(testSyslog("udp", "").Format(err, now))
+
+	expected := fmt.Sprintf(`<13>1 2024-05-06T07:08:09.123456Z host app %d %s [derp@32473 code="404" location="users.Get" detail="alice" detail="{\"id\":1}" detail="quote \" bracket \] slash \\"] user not found`,
+		os.Getpid(),
+		ringFingerprint(err),
+	)
+
+	if message != expected {
+		t.Errorf("unexpected message:\n%s\n%s", message, expected)
+	}
+}
+
+func TestSyslog_Severity(t *testing.T) {
+
+	expected := map[int]int{
+		200:  syslogSeverityInfo,
+		404:  syslogSeverityNotice,
+		410:  syslogSeverityNotice,
+		422:  syslogSeverityWarning,
+		500:  syslogSeverityError,
+		1001: syslogSeverityError,
+	}
+
+	for code, severity := range expected {
+		if actual := syslogSeverity(code); actual != severity {
+			t.Errorf("code %d: expected severity %d, got %d", code, severity, actual)
+		}
+	}
+
+	// PRI combines the facility and severity
+	reporter := testSyslog("udp", "")
+	reporter.Facility = SyslogFacilityLocal0
+
+	if message := string(reporter.Format(errors.New("plain"), time.Now())); !strings.HasPrefix(message, "<131>1 ") {
+		t.Errorf("unexpected PRI: %s", message)
+	}
+}
+
+func TestSyslog_ErrorSeverity(t *testing.T) {
+
+	// The error's own severity wins over its code
+	expected := map[string]int{
+		"critical": syslogSeverityCritical,
+		"error":    syslogSeverityError,
+		"warning":  syslogSeverityWarning,
+		"info":     syslogSeverityInfo,
+		"debug":    syslogSeverityDebug,
+		"":         syslogSeverityNotice, // No severity, so the (404) code is used
+	}
+
+	for name, severity := range expected {
+
+		err := severeError{codedError: codedError{code: 404, location: "users.Get"}, severity: name}
+
+		if actual := syslogErrorSeverity(err, 404); actual != severity {
+			t.Errorf("severity %q: expected %d, got %d", name, severity, actual)
+		}
+	}
+
+	// A critical (404) is not sent as a notice
+	reporter := testSyslog("udp", "")
+	err := severeError{codedError: codedError{code: 404, location: "users.Get"}, severity: "critical"}
+
+	if message := string(reporter.Format(err, time.Now())); !strings.HasPrefix(message, "<10>1 ") {
+		t.Errorf("unexpected PRI: %s", message)
+	}
+}
+
+func TestSyslog_DefaultFacility(t *testing.T) {
+
+	// A zero-value Syslog uses the "user" facility, not "kern"
+	reporter := &Syslog{Hostname: "host", AppName: "app"}
+
+	if message := string(reporter.Format(errors.New("plain"), time.Now())); !strings.HasPrefix(message, "<11>1 ") {
+		t.Errorf("unexpected PRI: %s", message)
+	}
+}
+
+func TestSyslog_HeaderFields(t *testing.T) {
+
+	if actual := syslogHeaderField("my app\n", 48); actual != "myapp" {
+		t.Errorf("expected spaces and controls to be removed, got %q", actual)
+	}
+
+	if actual := syslogHeaderField(" ", 48); actual != "-" {
+		t.Errorf("expected the NILVALUE, got %q", actual)
+	}
+
+	if actual := syslogHeaderField("abcdef", 3); actual != "abc" {
+		t.Errorf("expected truncation, got %q", actual)
+	}
+}
+
+func TestSyslog_UDP(t *testing.T) {
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Skipf("unable to listen on UDP: %s", err)
+	}
+
+	defer listener.Close()
+
+	reporter := testSyslog("udp", listener.LocalAddr().String())
+	defer reporter.Close()
+
+	reporter.Report(codedError{code: 502, location: "upstream.Call"})
+
+	buffer := make([]byte, 2048)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := listener.ReadFrom(buffer)
+
+	if err != nil {
+		t.Fatalf("no message received: %s", err)
+	}
+
+	if message := string(buffer[:length]); !strings.HasPrefix(message, "<11>1 ") || !strings.Contains(message, `location="upstream.Call"`) {
+		t.Errorf("unexpected message: %s", message)
+	}
+}
+
+func TestSyslog_ReportAfterClose(t *testing.T) {
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Skipf("unable to listen on UDP: %s", err)
+	}
+
+	defer listener.Close()
+
+	reporter := testSyslog("udp", listener.LocalAddr().String())
+
+	if err := reporter.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Late reports are dropped, instead of opening a connection that nobody will close
+	reporter.Report(codedError{code: 502, location: "upstream.Call"})
+
+	if reporter.connection != nil {
+		t.Error("expected no connection after Close")
+	}
+
+	buffer := make([]byte, 2048)
+	_ = listener.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	if _, _, err := listener.ReadFrom(buffer); err == nil {
+		t.Error("expected no message after Close")
+	}
+}
+
+func TestSyslog_TCP(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Skipf("unable to listen on TCP: %s", err)
+	}
+
+	defer listener.Close()
+
+	received := make(chan string, 2)
+
+	go func() {
+
+		connection, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer connection.Close()
+		reader := bufio.NewReader(connection)
+
+		// RFC 6587 octet counting: "LENGTH SP MESSAGE"
+		for index := 0; index < 2; index++ {
+
+			var length int
+
+			if _, err := fmt.Fscanf(reader, "%d ", &length); err != nil {
+				return
+			}
+
+			message := make([]byte, length)
+
+			if _, err := reader.Read(message); err != nil {
+				return
+			}
+
+			received <- string(message)
+		}
+	}()
+
+	reporter := testSyslog("tcp", listener.Addr().String())
+	defer reporter.Close()
+
+	reporter.Report(codedError{code: 404, location: "first"})
+	reporter.Report(codedError{code: 404, location: "second"})
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case message := <-received:
+			if !strings.Contains(message, `location="`+expected+`"`) {
+				t.Errorf("unexpected message: %s", message)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no message received")
+		}
+	}
+}
+
+func TestSyslog_Unixgram(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener, err := net.ListenPacket("unixgram", path)
+
+	if err != nil {
+		t.Skipf("unable to listen on a unix socket: %s", err)
+	}
+
+	defer listener.Close()
+
+	reporter := testSyslog("unixgram", path)
+	defer reporter.Close()
+
+	reporter.Report(errors.New("plain"))
+
+	buffer := make([]byte, 2048)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	length, _, err := listener.ReadFrom(buffer)
+
+	if err != nil {
+		t.Fatalf("no message received: %s", err)
+	}
+
+	if message := string(buffer[:length]); !strings.HasSuffix(message, `[derp@32473 code="500"] plain`) {
+		t.Errorf("unexpected message: %s", message)
+	}
+}
+
+func TestSyslog_Unreachable(t *testing.T) {
+
+	// Reporters swallow their own errors
+	reporter := testSyslog("unixgram", filepath.Join(t.TempDir(), "missing.sock"))
+	reporter.Report(errors.New("plain"))
+
+	if err := reporter.Close(); err != nil {
+		t.Errorf("expected no open connection, got %s", err)
+	}
+}
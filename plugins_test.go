@@ -81,6 +81,24 @@ func TestReporterList_Set(t *testing.T) {
 	assert.Same(t, first, list.slice()[0])
 }
 
+// TestReporterList_Reporters verifies that Reporters returns a copy that can restore the list later.
+func TestReporterList_Reporters(t *testing.T) {
+
+	var list ReporterList
+	assert.Equal(t, []Reporter{}, list.Reporters())
+
+	first := &countingPlugin{}
+	list.Set(first)
+
+	saved := list.Reporters()
+	saved[0] = &countingPlugin{}
+	assert.Same(t, first, list.slice()[0])
+
+	list.Clear()
+	list.Set(list.Reporters()...)
+	assert.Equal(t, 0, list.Len())
+}
+
 // TestReporterList_Report verifies that every registered reporter is invoked
 // once per call to Report, and that a nil error is never reported.
 func TestReporterList_Report(t *testing.T) {
@@ -192,3 +210,27 @@ type atomicCountingPlugin struct {
 func (plugin *atomicCountingPlugin) Report(error) {
 	plugin.count.Add(1)
 }
+
+// TestPlugins_Metrics verifies that derp errors expose what plugins.Metrics
+// reads through its local interfaces (it cannot import derp).
+func TestPlugins_Metrics(t *testing.T) {
+
+	metrics := plugins.NewMetrics()
+	metrics.Report(Wrap(NotFound("db.Load", "message"), "users.Get", "message"))
+
+	assert.Contains(t, metrics.Exposition(), `derp_errors_total{code="404",class="4xx",location="db.Load"} 1`)
+}
+
+// TestPlugins_Ring verifies that plugins.Ring groups derp errors by derp.Fingerprint.
+func TestPlugins_Ring(t *testing.T) {
+
+	ring := plugins.NewRing(10)
+	ring.Report(NotFound("users.Get", "user {id} not found", Param("id", 1)))
+	ring.Report(NotFound("users.Get", "user {id} not found", Param("id", 2)))
+
+	groups := ring.Groups()
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].Count)
+	assert.Equal(t, Fingerprint(NotFound("users.Get", "user {id} not found")), groups[0].Fingerprint)
+	assert.Equal(t, "user 2 not found", groups[0].Message)
+}
@@ -0,0 +1,83 @@
+package derp
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorIDHeader is the HTTP response header that WriteProblem uses to
+// return the unique ID of an error (see ErrorID) to the client.
+const ErrorIDHeader = "X-Error-Id"
+
+// Problem is an RFC 9457 "problem details" document, which describes an error
+// to HTTP clients using only values that are safe to show end users.
+type Problem struct {
+	Type    string `json:"type"`              // URL of a web page that describes this kind of error, or "about:blank"
+	Title   string `json:"title"`             // Localized, user-facing description of the error (see PublicMessage)
+	Status  int    `json:"status"`            // HTTP status code of the response (see HTTPStatus)
+	Code    string `json:"code,omitempty"`    // Symbolic name of the error code (see CodeName)
+	ErrorID string `json:"errorId,omitempty"` // Unique ID that customers can quote to support (see ErrorID)
+}
+
+// NewProblem describes any error as a Problem, localized for an Accept-Language
+// header.  The catalog may be nil (see PublicMessage).
+func NewProblem(err error, catalog Catalog, acceptLanguage string) Problem {
+
+	result := Problem{
+		Type:    URL(err),
+		Title:   PublicMessage(err, catalog, acceptLanguage),
+		Status:  httpStatus(ErrorCode(err)),
+		Code:    CodeName(err),
+		ErrorID: ErrorID(err),
+	}
+
+	// Errors without their own URL use the help page of their code
+	if result.Type == "" {
+		info, _ := LookupCode(ErrorCode(err))
+		result.Type = info.URL
+	}
+
+	if result.Type == "" {
+		result.Type = "about:blank"
+	}
+
+	return result
+}
+
+// WriteProblem writes any error to an HTTP response as an "application/problem+json"
+// document, localized for the request's Accept-Language header.  The ID of the error
+// (if any) is also written to the ErrorIDHeader.  The catalog may be nil.
+func WriteProblem(writer http.ResponseWriter, request *http.Request, err error, catalog Catalog) {
+
+	acceptLanguage := ""
+
+	if request != nil {
+		acceptLanguage = request.Header.Get("Accept-Language")
+	}
+
+	problem := NewProblem(err, catalog, acceptLanguage)
+
+	if problem.ErrorID != "" {
+		writer.Header().Set(ErrorIDHeader, problem.ErrorID)
+	}
+
+	writer.Header().Set("Content-Type", "application/problem+json")
+	writer.WriteHeader(problem.Status)
+
+	// The status has already been sent, so there is nothing left to do if this fails.
+	_ = json.NewEncoder(writer).Encode(problem)
+}
+
+// httpStatus converts an error code into a valid HTTP status code.  Registered codes use
+// their HTTPStatus, and other codes outside the HTTP range are reported as (500) Internal
+// Server Errors.
+func httpStatus(code int) int {
+
+	code = mappedStatus(code)
+
+	if code < 100 || code > 599 {
+		return codeInternalError
+	}
+
+	return code
+}
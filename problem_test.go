@@ -0,0 +1,73 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblem_New(t *testing.T) {
+
+	err := NotFound("location", "developer text", WithID("error-id"))
+	err.URL = "https://example.com/errors/missing"
+	problem := NewProblem(err, nil, "")
+
+	require.Equal(t, Problem{
+		Type:    "https://example.com/errors/missing",
+		Title:   "Not found",
+		Status:  404,
+		Code:    "not_found",
+		ErrorID: "error-id",
+	}, problem)
+
+	// Wrapped errors still expose the ID of their root cause
+	require.Equal(t, "error-id", NewProblem(Wrap(err, "outer", "message"), nil, "").ErrorID)
+}
+
+func TestProblem_Defaults(t *testing.T) {
+
+	problem := NewProblem(errors.New("developer text"), nil, "")
+	require.Equal(t, Problem{Type: "about:blank", Title: "Something went wrong", Status: 500, Code: "internal"}, problem)
+
+	// Application codes outside the HTTP range become (500) Internal Server Errors
+	require.Equal(t, 500, NewProblem(newError(1001, "location", "message"), nil, "").Status)
+}
+
+func TestProblem_RegisteredCode(t *testing.T) {
+
+	useCode(t, CodeInfo{Code: 10042, Name: "billing.card_declined", HTTPStatus: 402, Message: "Your card was declined", URL: "https://example.com/errors/card-declined"})
+
+	problem := NewProblem(newError(10042, "location", "developer text"), nil, "")
+
+	require.Equal(t, Problem{
+		Type:   "https://example.com/errors/card-declined",
+		Title:  "Your card was declined",
+		Status: 402,
+		Code:   "billing.card_declined",
+	}, problem)
+}
+
+func TestProblem_Write(t *testing.T) {
+
+	catalog := NewMemoryCatalog("en")
+	catalog.Set("fr", "user.missing", "Utilisateur introuvable")
+
+	request := httptest.NewRequest("GET", "/users/123", nil)
+	request.Header.Set("Accept-Language", "fr")
+	recorder := httptest.NewRecorder()
+
+	WriteProblem(recorder, request, NotFound("location", "developer text", WithID("error-id"), WithMessageKey("user.missing")), catalog)
+
+	require.Equal(t, 404, recorder.Code)
+	require.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "error-id", recorder.Header().Get(ErrorIDHeader))
+	require.NotContains(t, recorder.Body.String(), "developer text")
+
+	var problem Problem
+	require.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	require.Equal(t, "Utilisateur introuvable", problem.Title)
+	require.Equal(t, "error-id", problem.ErrorID)
+}
@@ -0,0 +1,132 @@
+package derp
+
+import (
+	"errors"
+	"net/http"
+)
+
+// PublicMessage returns the text that is safe to show an end user for any error, localized for
+// an Accept-Language header.  Error.Message is developer text, so it is never returned.  Instead:
+//
+//  1. the error's message key (see WithMessageKey) is looked up in the catalog,
+//  2. then the error's public message (see WithPublicMessage) is used as written,
+//  3. then the error code's key (see CodeMessageKey) is looked up in the catalog,
+//  4. and finally the default message of the registered error code (see RegisterCode) is used.
+//
+// The catalog may be nil, in which case only the public message and built-in messages are used.
+func PublicMessage(err error, catalog Catalog, acceptLanguage string) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	languages := ParseAcceptLanguage(acceptLanguage)
+
+	if key := MessageKey(err); key != "" {
+		if message, ok := lookupLanguages(catalog, languages, key); ok {
+			return message
+		}
+	}
+
+	if getter, ok := AsType[PublicMessageGetter](err); ok {
+		if message := getter.GetPublicMessage(); message != "" {
+			return message
+		}
+	}
+
+	code := ErrorCode(err)
+
+	if message, ok := lookupLanguages(catalog, languages, CodeMessageKey(code)); ok {
+		return message
+	}
+
+	return codeMessage(code)
+}
+
+// codeMessage returns the default message of a registered error code.  Codes without a
+// message fall back to the message of the HTTP status that they map onto, and then to
+// a message for their class.
+func codeMessage(code int) string {
+
+	if info, ok := LookupCode(code); ok && info.Message != "" {
+		return info.Message
+	}
+
+	status := httpStatus(code)
+
+	if info, ok := LookupCode(status); ok && info.Message != "" {
+		return info.Message
+	}
+
+	// Unknown HTTP codes are described by their class, and anything else as an internal error
+	if status >= 400 && status < 500 {
+
+		if text := http.StatusText(status); text != "" {
+			return text
+		}
+
+		status = codeBadRequestError
+
+	} else {
+		status = codeInternalError
+	}
+
+	if info, ok := LookupCode(status); ok && info.Message != "" {
+		return info.Message
+	}
+
+	return http.StatusText(status)
+}
+
+// MessageKey retrieves the catalog key (set by WithMessageKey) of any error.
+func MessageKey(err error) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	if getter, ok := AsType[MessageKeyGetter](err); ok {
+		return getter.GetMessageKey()
+	}
+
+	return ""
+}
+
+// HasMessageKey returns TRUE if the catalog key was applied (via WithMessageKey)
+// to the error, or to any error that it wraps.
+func HasMessageKey(err error, key string) bool {
+
+	if key == "" {
+		return false
+	}
+
+	for NotNil(err) {
+
+		if getter, ok := err.(MessageKeyGetter); ok && getter.GetMessageKey() == key {
+			return true
+		}
+
+		err = errors.Unwrap(err)
+	}
+
+	return false
+}
+
+// lookupLanguages looks up a key in each of the preferred languages,
+// and then in the catalog's default language.
+func lookupLanguages(catalog Catalog, languages []string, key string) (string, bool) {
+
+	if catalog == nil {
+		return "", false
+	}
+
+	for _, language := range languages {
+		if message, ok := catalog.Lookup(language, key); ok {
+			return message, true
+		}
+	}
+
+	return catalog.Lookup("", key)
+}
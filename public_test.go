@@ -0,0 +1,80 @@
+package derp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicMessage_Key(t *testing.T) {
+
+	catalog := NewMemoryCatalog("en")
+	catalog.Set("en", "user.missing", "We couldn't find that user.")
+	catalog.Set("fr", "user.missing", "Utilisateur introuvable.")
+
+	err := NotFound("location", "user 123 missing from users table", WithMessageKey("user.missing"))
+
+	require.Equal(t, "Utilisateur introuvable.", PublicMessage(err, catalog, "fr-FR, en;q=0.5"))
+	require.Equal(t, "We couldn't find that user.", PublicMessage(err, catalog, "de"))
+	require.Equal(t, "We couldn't find that user.", PublicMessage(err, catalog, ""))
+
+	// Keys survive wrapping, including standard wrappers
+	wrapped := fmt.Errorf("outer: %w", Wrap(err, "outer", "message"))
+	require.Equal(t, "user.missing", MessageKey(wrapped))
+	require.Equal(t, "Utilisateur introuvable.", PublicMessage(wrapped, catalog, "fr"))
+}
+
+func TestPublicMessage_Fallbacks(t *testing.T) {
+
+	catalog := NewMemoryCatalog("en")
+	catalog.Set("fr", CodeMessageKey(404), "Introuvable")
+
+	// Unknown keys fall back to the public message
+	err := NotFound("location", "developer text", WithMessageKey("unknown"), WithPublicMessage("That page is missing"))
+	require.Equal(t, "That page is missing", PublicMessage(err, catalog, "fr"))
+
+	// ...then to the catalog's message for the code
+	err = NotFound("location", "developer text")
+	require.Equal(t, "Introuvable", PublicMessage(err, catalog, "fr"))
+
+	// ...and then to derp's own message for the code
+	require.Equal(t, "Not found", PublicMessage(err, catalog, "en"))
+	require.Equal(t, "Not found", PublicMessage(err, nil, ""))
+	require.Equal(t, "Something went wrong", PublicMessage(errors.New("developer text"), nil, ""))
+	require.Equal(t, "Something went wrong", PublicMessage(newError(1001, "location", "developer text"), nil, ""))
+	require.Equal(t, "Payment Required", PublicMessage(newError(402, "location", "developer text"), nil, ""))
+	require.Equal(t, "Bad request", PublicMessage(newError(498, "location", "developer text"), nil, ""))
+	require.Equal(t, "The request was canceled", PublicMessage(newError(CodeClientClosedRequest, "location", "developer text"), nil, ""))
+	require.Equal(t, "", PublicMessage(nil, catalog, "en"))
+}
+
+func TestHasMessageKey(t *testing.T) {
+
+	err := NotFound("inner", "developer text", WithMessageKey("user.missing"))
+	wrapped := fmt.Errorf("outer: %w", Wrap(err, "outer", "message", WithMessageKey("request.failed")))
+
+	require.True(t, HasMessageKey(err, "user.missing"))
+	require.True(t, HasMessageKey(wrapped, "user.missing"))
+	require.True(t, HasMessageKey(wrapped, "request.failed"))
+	require.False(t, HasMessageKey(wrapped, "unknown"))
+	require.False(t, HasMessageKey(wrapped, ""))
+	require.False(t, HasMessageKey(nil, "user.missing"))
+}
+
+func TestPublicMessage_Wrapped(t *testing.T) {
+
+	inner := NotFound("inner", "developer text", WithPublicMessage("Inner message"))
+
+	require.Equal(t, "Inner message", PublicMessage(Wrap(inner, "outer", "message"), nil, ""))
+	require.Equal(t, "Outer message", PublicMessage(Wrap(inner, "outer", "message", WithPublicMessage("Outer message")), nil, ""))
+}
+
+func TestPublicMessage_Options(t *testing.T) {
+
+	err := Internal("location", "message", WithPublicMessage("public"), WithMessageKey("key"))
+	require.Equal(t, "public", err.PublicMessage)
+	require.Equal(t, "key", err.MessageKey)
+	require.False(t, err.IsZero())
+}
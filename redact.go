@@ -0,0 +1,560 @@
+package derp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// redaction holds the Redactor that is applied to every error before it is
+// serialized or reported.  A nil value disables redaction entirely.
+var redaction atomic.Pointer[Redactor]
+
+// Redactor removes sensitive values (credentials, tokens, and personal data) from errors
+// before they leave the process.  It is applied by Serialize, by the JSON encoding of
+// Error and HTTPError, and by Report before an error reaches any reporter.
+//
+// A Redactor is read concurrently by every goroutine that reports an error, so it must
+// not be modified once it has been passed to SetRedactor.  Build a new one instead.
+type Redactor struct {
+	Headers     []string         // Header names (case-insensitive) whose values are always replaced
+	HeaderKeys  []*regexp.Regexp // Header names matching any of these patterns are also replaced
+	DetailKeys  []*regexp.Regexp // Map keys in Details (and URL query parameters, and form and JSON bodies) whose values are replaced
+	Patterns    []*regexp.Regexp // Substrings of messages and string details that are scrubbed
+	CardNumbers bool             // TRUE if payment card numbers (that pass the Luhn check) are also scrubbed
+	Replacement string           // Text that replaces each redacted value
+}
+
+// cardNumberPattern matches runs of 13-19 digits, optionally separated by spaces or dashes,
+// which are scrubbed only if they pass the Luhn check (see isCardNumber).
+var cardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+// NewRedactor returns a Redactor populated with derp's default rules:
+// credential headers, credential-looking detail keys, and scrubbing of
+// bearer tokens, email addresses, and payment card numbers.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		Headers: []string{
+			"Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"X-Api-Key",
+		},
+		HeaderKeys: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)(token|secret|password)`),
+		},
+		DetailKeys: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)(password|passwd|secret|token|api[-_]?key|authorization|cookie|session)`),
+		},
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`),                      // OAuth bearer tokens
+			regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),       // email addresses
+			regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`), // JSON Web Tokens
+		},
+		CardNumbers: true,
+		Replacement: "[REDACTED]",
+	}
+}
+
+// SetRedactor replaces the Redactor that is applied to every serialized and reported error.
+// Passing nil disables redaction.
+func SetRedactor(redactor *Redactor) {
+	redaction.Store(redactor)
+}
+
+// currentRedactor returns the Redactor in effect, which may be nil.
+func currentRedactor() *Redactor {
+	return redaction.Load()
+}
+
+// Redact returns a copy of the provided error with all sensitive values removed,
+// using the Redactor configured by SetRedactor.  The original error is never modified.
+func Redact(err error) error {
+	return currentRedactor().Redact(err)
+}
+
+// Redact returns a copy of the provided error, and every error that it wraps, with
+// sensitive headers, detail values, and message text replaced.  Every derp error in the
+// chain is rebuilt, even beneath standard wrappers such as fmt.Errorf with %w.  Other
+// errors cannot be rewritten without changing their type, so any that wrap another error,
+// or whose text contains sensitive values, are replaced by a copy with scrubbed text.
+// The copy still unwraps to the rest of the (redacted) chain, so errors.Is and errors.As
+// keep working.  A nil Redactor returns the error unchanged.
+func (redactor *Redactor) Redact(err error) error {
+
+	// double nil check to make nilaway happy
+	if redactor == nil || IsNil(err) || err == nil {
+		return err
+	}
+
+	switch typed := err.(type) {
+
+	case Error:
+		return redactor.redactError(typed, true)
+
+	case *Error:
+		result := redactor.redactError(*typed, true)
+		return &result
+
+	case HTTPError:
+		return redactor.redactHTTPError(typed, true)
+
+	case *HTTPError:
+		result := redactor.redactHTTPError(*typed, true)
+		return &result
+
+	case interface{ Unwrap() error }:
+		return redactedError{
+			message: redactor.Scrub(err.Error()),
+			wrapped: redactor.Redact(typed.Unwrap()),
+		}
+
+	case interface{ Unwrap() []error }:
+		wrapped := typed.Unwrap()
+		result := redactedErrors{message: redactor.Scrub(err.Error()), wrapped: make([]error, len(wrapped))}
+
+		for index, item := range wrapped {
+			result.wrapped[index] = redactor.Redact(item)
+		}
+
+		return result
+	}
+
+	// RULE: Clean errors at the end of the chain are kept, so that sentinels (such as context.Canceled) still match with errors.Is
+	if message := redactor.Scrub(err.Error()); message != err.Error() {
+		return redactedError{message: message}
+	}
+
+	return err
+}
+
+// RedactHeader returns a copy of the provided header with the values of all
+// sensitive headers replaced.  The original header is never modified, because
+// an HTTPError shares its headers with the original request and response.
+func (redactor *Redactor) RedactHeader(header http.Header) http.Header {
+
+	if redactor == nil || header == nil {
+		return header
+	}
+
+	result := make(http.Header, len(header))
+
+	for name, values := range header {
+
+		if redactor.isSensitiveHeader(name) {
+			result[name] = []string{redactor.Replacement}
+			continue
+		}
+
+		result[name] = make([]string, len(values))
+
+		for index, value := range values {
+			result[name][index] = redactor.Scrub(value)
+		}
+	}
+
+	return result
+}
+
+// Scrub replaces every substring of the provided text that matches one of the
+// Redactor's Patterns, along with payment card numbers if CardNumbers is set.
+func (redactor *Redactor) Scrub(text string) string {
+
+	if redactor == nil {
+		return text
+	}
+
+	for _, pattern := range redactor.Patterns {
+		text = pattern.ReplaceAllLiteralString(text, redactor.Replacement)
+	}
+
+	// RULE: Long numbers are only redacted if they pass the Luhn check, so that
+	// order numbers, timestamps, and other IDs of the same length survive.
+	if redactor.CardNumbers {
+		text = cardNumberPattern.ReplaceAllStringFunc(text, func(match string) string {
+
+			if isCardNumber(match) {
+				return redactor.Replacement
+			}
+
+			return match
+		})
+	}
+
+	return text
+}
+
+// isCardNumber returns TRUE if the digits in a value pass the Luhn check
+// that every payment card number satisfies.
+func isCardNumber(value string) bool {
+
+	sum := 0
+	double := false
+
+	// Digits are read from right to left, doubling every second one
+	for index := len(value) - 1; index >= 0; index-- {
+
+		digit := value[index]
+
+		if digit < '0' || digit > '9' {
+			continue
+		}
+
+		number := int(digit - '0')
+
+		if double {
+			number *= 2
+
+			if number > 9 {
+				number -= 9
+			}
+		}
+
+		sum += number
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// redactError redacts the fields of a single derp.Error, and optionally
+// the rest of the chain that it wraps.
+func (redactor *Redactor) redactError(err Error, deep bool) Error {
+
+	err.Message = redactor.Scrub(err.Message)
+
+	if err.Details != nil {
+		details := make([]any, len(err.Details))
+
+		for index, detail := range err.Details {
+			details[index] = redactor.redactValue(detail)
+		}
+
+		err.Details = details
+	}
+
+	if err.Fields != nil {
+		err.Fields = redactor.redactValue(err.Fields).(map[string]any)
+	}
+
+	if err.Params != nil {
+		err.Params = redactor.redactValue(err.Params).(map[string]any)
+	}
+
+	if deep {
+		err.WrappedValue = redactor.Redact(err.WrappedValue)
+	}
+
+	return err
+}
+
+// redactHTTPError redacts the request and response of a single HTTPError,
+// and optionally the rest of the chain that it wraps.
+func (redactor *Redactor) redactHTTPError(err HTTPError, deep bool) HTTPError {
+
+	err.Request.URL = redactor.redactURL(err.Request.URL)
+	err.Request.Header = redactor.RedactHeader(err.Request.Header)
+	err.Request.Body = redactor.redactBody(err.Request.Body, err.Request.Header.Get("Content-Type"))
+	err.Response.Body = redactor.redactBody(err.Response.Body, err.Response.Header.Get("Content-Type"))
+	err.Request.Header = redactor.RedactHeader(err.Request.Header)
+	err.Response.Header = redactor.RedactHeader(err.Response.Header)
+
+	if deep {
+		err.WrappedValue = redactor.Redact(err.WrappedValue)
+	}
+
+	return err
+}
+
+// redactURL removes passwords and sensitive query parameters from a URL.
+func (redactor *Redactor) redactURL(value string) string {
+
+	parsed, err := url.Parse(value)
+
+	// Unparseable URLs are scrubbed as plain text
+	if err != nil {
+		return redactor.Scrub(value)
+	}
+
+	// RULE: Only re-encode the URL when something was redacted, because
+	// encoding sorts the query parameters and would otherwise rewrite every URL.
+	changed := false
+
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redactor.Replacement)
+		changed = true
+	}
+
+	if query := parsed.Query(); len(query) > 0 {
+
+		for key := range query {
+			if redactor.isSensitiveKey(key) {
+				query[key] = []string{redactor.Replacement}
+				changed = true
+			}
+		}
+
+		if changed {
+			parsed.RawQuery = query.Encode()
+		}
+	}
+
+	if !changed {
+		return value
+	}
+
+	return parsed.String()
+}
+
+// jsonStringMember matches a member of a JSON object whose value is a string, so
+// that truncated JSON bodies (which cannot be decoded) can still be redacted by key.
+var jsonStringMember = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// redactBody removes sensitive values from a captured request or response body.  Form
+// and JSON bodies are redacted by key (see DetailKeys), just like Details, and then every
+// body is scrubbed as text.
+func (redactor *Redactor) redactBody(body string, contentType string) string {
+
+	if body == "" {
+		return body
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+
+	case mediaType == "application/x-www-form-urlencoded":
+		body = redactor.redactFormBody(body)
+
+	case isJSONMediaType(mediaType):
+		body = redactor.redactJSONBody(body)
+	}
+
+	return redactor.Scrub(body)
+}
+
+// redactFormBody replaces the values of sensitive keys in a URL-encoded form.  Pairs are
+// rewritten in place, so that the rest of the body keeps its original order and encoding.
+func (redactor *Redactor) redactFormBody(body string) string {
+
+	if _, err := url.ParseQuery(body); err != nil {
+		return body
+	}
+
+	pairs := strings.Split(body, "&")
+
+	for index, pair := range pairs {
+
+		key, _, _ := strings.Cut(pair, "=")
+
+		if unescaped, err := url.QueryUnescape(key); err == nil && redactor.isSensitiveKey(unescaped) {
+			pairs[index] = key + "=" + redactor.Replacement
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// redactJSONBody replaces the values of sensitive keys in a JSON document.
+func (redactor *Redactor) redactJSONBody(body string) string {
+
+	var value any
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	// Truncated documents cannot be decoded, so only their string members are redacted
+	if decoder.Decode(&value) != nil || decoder.More() {
+		return jsonStringMember.ReplaceAllStringFunc(body, func(member string) string {
+
+			match := jsonStringMember.FindStringSubmatch(member)
+
+			if key, err := strconv.Unquote(`"` + match[1] + `"`); err == nil && redactor.isSensitiveKey(key) {
+				return `"` + match[1] + `"` + match[2] + strconv.Quote(redactor.Replacement)
+			}
+
+			return member
+		})
+	}
+
+	// RULE: Only re-encode the body when something was redacted, because encoding sorts object keys.
+	redacted := redactor.redactValue(value)
+
+	if reflect.DeepEqual(redacted, value) {
+		return body
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "\t")
+
+	if encoder.Encode(redacted) != nil {
+		return body
+	}
+
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// redactValue returns a redacted copy of a single detail value.  Maps and
+// slices are copied, because Details are often shared with the caller.
+func (redactor *Redactor) redactValue(value any) any {
+
+	switch typed := value.(type) {
+
+	case string:
+		return redactor.Scrub(typed)
+
+	case error:
+		return redactor.Redact(typed)
+
+	case http.Header:
+		return redactor.RedactHeader(typed)
+
+	case map[string]string:
+		result := make(map[string]string, len(typed))
+
+		for key, item := range typed {
+			if redactor.isSensitiveKey(key) {
+				result[key] = redactor.Replacement
+			} else {
+				result[key] = redactor.Scrub(item)
+			}
+		}
+
+		return result
+
+	case map[string]any:
+		result := make(map[string]any, len(typed))
+
+		for key, item := range typed {
+			if redactor.isSensitiveKey(key) {
+				result[key] = redactor.Replacement
+			} else {
+				result[key] = redactor.redactValue(item)
+			}
+		}
+
+		return result
+
+	case []any:
+		result := make([]any, len(typed))
+
+		for index, item := range typed {
+			result[index] = redactor.redactValue(item)
+		}
+
+		return result
+	}
+
+	return value
+}
+
+/******************************************
+ * Redacted Copies of Other Errors
+ ******************************************/
+
+// redactedError replaces an error that Redact cannot rewrite, such as the result of
+// fmt.Errorf.  It keeps the scrubbed text of the original, and wraps a redacted copy
+// of whatever the original wrapped.
+type redactedError struct {
+	message string
+	wrapped error
+}
+
+// Error implements the error interface.
+func (err redactedError) Error() string {
+	return err.message
+}
+
+// Unwrap supports Go 1.13+ error unwrapping
+func (err redactedError) Unwrap() error {
+	return err.wrapped
+}
+
+// MarshalJSON implements the json.Marshaler interface, using the same
+// names as derp.Error so that the wrapped chain is still visible.
+func (err redactedError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message      string `json:"message"`
+		WrappedValue error  `json:"innerError,omitempty"`
+	}{
+		Message:      err.message,
+		WrappedValue: err.wrapped,
+	})
+}
+
+// LogValue implements the slog.LogValuer interface, so that the wrapped chain is logged too.
+func (err redactedError) LogValue() slog.Value {
+
+	if IsNil(err.wrapped) {
+		return slog.GroupValue(slog.String("message", err.message))
+	}
+
+	return slog.GroupValue(slog.String("message", err.message), innerErrorAttr(err.wrapped))
+}
+
+// redactedErrors replaces an error that wraps several others (such as the result of
+// errors.Join), just as redactedError does for errors that wrap only one.
+type redactedErrors struct {
+	message string
+	wrapped []error
+}
+
+// Error implements the error interface.
+func (err redactedErrors) Error() string {
+	return err.message
+}
+
+// Unwrap supports Go 1.20+ error unwrapping
+func (err redactedErrors) Unwrap() []error {
+	return err.wrapped
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (err redactedErrors) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Message      string  `json:"message"`
+		WrappedValue []error `json:"innerErrors,omitempty"`
+	}{
+		Message:      err.message,
+		WrappedValue: err.wrapped,
+	})
+}
+
+// isSensitiveHeader returns TRUE if the named header must be redacted.
+func (redactor *Redactor) isSensitiveHeader(name string) bool {
+
+	for _, header := range redactor.Headers {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+
+	return matchesAny(redactor.HeaderKeys, name)
+}
+
+// isSensitiveKey returns TRUE if the value stored under this key must be redacted.
+func (redactor *Redactor) isSensitiveKey(key string) bool {
+	return matchesAny(redactor.DetailKeys, key)
+}
+
+// matchesAny returns TRUE if the value matches at least one of the patterns.
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
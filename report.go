@@ -1,15 +1,37 @@
 package derp
 
 // Report takes ANY error (hopefully a derp error) and attempts to report it
-// via all configured error reporting mechanisms.
+// via all configured error reporting mechanisms.  Sensitive values are removed
+// by the current Redactor before the error reaches any reporter, and errors
+// below the minimum severity (see SetMinSeverity) are not reported at all.
+//
+// A reporter that panics never crashes the caller: the panic is recovered, and
+// described to the fallback reporter (see SetFallbackReporter).  Wrap slow
+// reporters with TimeoutReporter to bound how long Report can block.
 func Report(err error) {
 
 	// If the error is NOT nil, then send "Report" to each installed reporter.
-	// The loaded list is immutable, so this range is safe against concurrent Set/Add/Clear.
 	if NotNil(err) {
-		for _, reporter := range Plugins.slice() {
-			reporter.Report(err)
-		}
+		report(&Plugins, err)
+	}
+}
+
+// report sends a non-nil error to every reporter in the list.  It is shared by
+// Report and Scope.Report, so that both filter and redact errors the same way.
+func report(list *ReporterList, err error) {
+
+	if ErrorSeverity(err) < Severity(minSeverity.Load()) {
+		return
+	}
+
+	// Redacted once here, so that every reporter receives the same scrubbed copy.
+	redacted := Redact(err)
+
+	// The loaded list is immutable, so this range is safe against concurrent Set/Add/Clear.
+	set := list.set()
+
+	for index, reporter := range set.reporters {
+		deliver(reporter, set.stats[index], redacted)
 	}
 }
 
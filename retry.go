@@ -0,0 +1,201 @@
+package derp
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how Retry spaces out repeated attempts of a failing operation.
+// Zero values are replaced by the defaults used in NewRetryPolicy, except for
+// Jitter, where zero disables randomization.
+type RetryPolicy struct {
+	MaxAttempts  int           // Maximum number of attempts, including the first one
+	InitialDelay time.Duration // Delay before the second attempt
+	MaxDelay     time.Duration // Upper bound on any computed backoff delay
+	Multiplier   float64       // Factor by which the delay grows after each attempt
+	Jitter       float64       // Fraction (0-1) of each delay that is randomized, so that clients do not retry in lockstep
+	MaxElapsed   time.Duration // Upper bound on the total time spent retrying, including waits
+}
+
+// NewRetryPolicy returns a RetryPolicy with sensible defaults: up to 5 attempts,
+// starting at 100ms and doubling up to 10s, with 20% jitter and a 1 minute cap.
+func NewRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  5,
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxElapsed:   time.Minute,
+	}
+}
+
+// Retry calls fn until it succeeds, returns an error that is not retryable, or the policy is
+// exhausted.  Waits grow exponentially (with jitter) unless the error carries its own
+// retry-after duration, which is honored instead.  Each call receives a context that records
+// its attempt number (see AttemptFromContext), which Transport copies into its HTTPErrors.
+//
+// Only errors that are retryable (see IsRetryable) are retried, so an error created
+// with WithRetryable(false) stops the loop immediately.
+//
+// When every attempt fails, Retry returns a derp.Error that wraps the last error (so its
+// code and classification are preserved) and lists every attempt in its Details.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(context.Context) error) error {
+
+	const location = "derp.Retry"
+
+	policy = policy.withDefaults()
+	started := time.Now()
+	attempts := make([]any, 0, policy.MaxAttempts)
+
+	var err error
+	attempt := 0
+
+	for {
+		attempt++
+
+		err = fn(ContextWithAttempt(ctx, attempt))
+
+		if IsNil(err) {
+			return nil
+		}
+
+		attempts = append(attempts, "attempt "+strconv.Itoa(attempt)+": "+err.Error())
+
+		if !IsRetryable(err) || attempt >= policy.MaxAttempts {
+			break
+		}
+
+		// Stop early if waiting would exceed the total time allowed
+		delay := policy.delay(attempt, err)
+
+		if time.Since(started)+delay > policy.MaxElapsed {
+			attempts = append(attempts, "stopped: next attempt would exceed "+policy.MaxElapsed.String())
+			break
+		}
+
+		if waitError := wait(ctx, delay); waitError != nil {
+			attempts = append(attempts, "stopped: "+waitError.Error())
+			break
+		}
+	}
+
+	return Wrap(err, location, "Operation failed after "+strconv.Itoa(attempt)+" attempt(s)", attempts...)
+}
+
+// withDefaults replaces every zero value in the policy with its default.
+func (policy RetryPolicy) withDefaults() RetryPolicy {
+
+	defaults := NewRetryPolicy()
+
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = defaults.InitialDelay
+	}
+
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = defaults.MaxDelay
+	}
+
+	if policy.Multiplier < 1 {
+		policy.Multiplier = defaults.Multiplier
+	}
+
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		policy.Jitter = defaults.Jitter
+	}
+
+	if policy.MaxElapsed <= 0 {
+		policy.MaxElapsed = defaults.MaxElapsed
+	}
+
+	return policy
+}
+
+// delay returns how long to wait after the given (1-based) attempt failed with err.
+func (policy RetryPolicy) delay(attempt int, err error) time.Duration {
+
+	// The server knows best: honor an explicit retry-after duration when one was sent.
+	if retryAfter, ok := retryAfterHint(err); ok {
+		return retryAfter
+	}
+
+	delay := float64(policy.InitialDelay)
+
+	for index := 1; index < attempt; index++ {
+		delay = delay * policy.Multiplier
+
+		if delay > float64(policy.MaxDelay) {
+			delay = float64(policy.MaxDelay)
+			break
+		}
+	}
+
+	// Randomize within +/- Jitter of the computed delay
+	delay = delay * (1 + policy.Jitter*(2*rand.Float64()-1)) //nolint:gosec // jitter does not need a secure random source
+
+	return time.Duration(delay)
+}
+
+// retryAfterHint returns the retry-after duration carried by the error, if any.
+// It walks the chain, so that the outermost explicit value wins.
+func retryAfterHint(err error) (time.Duration, bool) {
+
+	for NotNil(err) {
+
+		switch typed := err.(type) {
+
+		case Error:
+			if typed.RetryAfter > 0 {
+				return typed.RetryAfter, true
+			}
+
+		case *Error:
+			if typed.RetryAfter > 0 {
+				return typed.RetryAfter, true
+			}
+
+		// RULE: An HTTPError without retry headers reports a 1 hour default, which suits a
+		// rate-limited caller but not a retry loop.  Only a header that was actually sent counts.
+		case HTTPError:
+			return typed.retryAfter()
+
+		case *HTTPError:
+			return typed.retryAfter()
+
+		case RetryAfterGetter:
+			if retryAfter := typed.GetRetryAfter(); retryAfter > 0 {
+				return retryAfter, true
+			}
+		}
+
+		unwrapper, ok := err.(Unwrapper)
+
+		if !ok {
+			break
+		}
+
+		err = unwrapper.Unwrap()
+	}
+
+	return 0, false
+}
+
+// wait pauses for the given duration, returning early with an error if the context is canceled.
+func wait(ctx context.Context, delay time.Duration) error {
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
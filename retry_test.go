@@ -0,0 +1,207 @@
+package derp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetryPolicy keeps test delays short and deterministic.
+func fastRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  4,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     5 * time.Millisecond,
+		Multiplier:   2,
+		MaxElapsed:   time.Second,
+	}
+}
+
+func TestRetry_Success(t *testing.T) {
+
+	calls := 0
+
+	err := Retry(context.Background(), fastRetryPolicy(), func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetry_EventualSuccess(t *testing.T) {
+
+	attempts := []int{}
+
+	err := Retry(context.Background(), fastRetryPolicy(), func(ctx context.Context) error {
+		attempts = append(attempts, AttemptFromContext(ctx))
+
+		if len(attempts) < 3 {
+			return newError(codeServiceUnavailableError, "location", "unavailable")
+		}
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, attempts)
+}
+
+func TestRetry_Exhausted(t *testing.T) {
+
+	calls := 0
+
+	err := Retry(context.Background(), fastRetryPolicy(), func(context.Context) error {
+		calls++
+		return BadGateway("location", "upstream failed")
+	})
+
+	require.Equal(t, 4, calls)
+	require.True(t, IsBadGateway(err))
+	require.Equal(t, "derp.Retry", Location(err))
+	require.Equal(t, "Operation failed after 4 attempt(s)", Message(err))
+	require.Len(t, Details(err), 4)
+	require.Equal(t, "attempt 1: location: upstream failed", Details(err)[0])
+	require.Equal(t, "upstream failed", RootMessage(err))
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+
+	calls := 0
+
+	err := Retry(context.Background(), fastRetryPolicy(), func(context.Context) error {
+		calls++
+		return NotFound("location", "missing")
+	})
+
+	require.Equal(t, 1, calls)
+	require.True(t, IsNotFound(err))
+}
+
+func TestRetry_WithRetryable(t *testing.T) {
+
+	calls := 0
+
+	// An explicit decision overrides the default for the error's code
+	err := Retry(context.Background(), fastRetryPolicy(), func(context.Context) error {
+		calls++
+		return BadGateway("location", "upstream rejected the payload", WithRetryable(false))
+	})
+
+	require.Equal(t, 1, calls)
+	require.True(t, IsBadGateway(err))
+}
+
+func TestRetry_WithRetryAfter(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	policy.InitialDelay = time.Hour
+
+	// WithRetryAfter replaces the computed backoff, even when wrapping an HTTPError
+	inner := HTTPError{Response: HTTPResponseReport{StatusCode: 503}}
+	err := Wrap(inner, "location", "unavailable", WithRetryAfter(3*time.Millisecond))
+	require.Equal(t, 3*time.Millisecond, policy.withDefaults().delay(1, err))
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	policy.InitialDelay = time.Hour
+
+	// An explicit Retry-After header replaces the (very long) computed backoff
+	withHeader := HTTPError{Response: HTTPResponseReport{
+		StatusCode: 429,
+		Header:     http.Header{"Retry-After": []string{"0"}},
+	}}
+	require.Equal(t, time.Duration(0), policy.withDefaults().delay(1, withHeader))
+
+	// Without a header, the 1 hour HTTPError default is ignored in favor of backoff
+	policy = fastRetryPolicy()
+	withoutHeader := HTTPError{Response: HTTPResponseReport{StatusCode: 503}}
+	require.Equal(t, time.Millisecond, policy.delay(1, withoutHeader))
+}
+
+func TestRetry_Backoff(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	err := errors.New("generic")
+
+	require.Equal(t, 1*time.Millisecond, policy.delay(1, err))
+	require.Equal(t, 2*time.Millisecond, policy.delay(2, err))
+	require.Equal(t, 4*time.Millisecond, policy.delay(3, err))
+	require.Equal(t, 5*time.Millisecond, policy.delay(4, err))
+	require.Equal(t, 5*time.Millisecond, policy.delay(10, err))
+}
+
+func TestRetry_Jitter(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	policy.InitialDelay = 100 * time.Millisecond
+	policy.Jitter = 0.5
+
+	for index := 0; index < 100; index++ {
+		delay := policy.delay(1, errors.New("generic"))
+		require.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		require.LessOrEqual(t, delay, 150*time.Millisecond)
+	}
+}
+
+func TestRetry_MaxElapsed(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	policy.InitialDelay = time.Hour
+	policy.MaxDelay = time.Hour
+
+	calls := 0
+
+	err := Retry(context.Background(), policy, func(context.Context) error {
+		calls++
+		return Timeout("location", "slow")
+	})
+
+	// The first wait would exceed MaxElapsed, so only one attempt is made
+	require.Equal(t, 1, calls)
+	require.Equal(t, codeTimeout, ErrorCode(err))
+	require.Len(t, Details(err), 2)
+}
+
+func TestRetry_Canceled(t *testing.T) {
+
+	policy := fastRetryPolicy()
+	policy.InitialDelay = 500 * time.Millisecond
+	policy.MaxDelay = 500 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+
+	err := Retry(ctx, policy, func(context.Context) error {
+		calls++
+		return BadGateway("location", "upstream failed")
+	})
+
+	require.Equal(t, 1, calls)
+	require.True(t, IsBadGateway(err))
+	require.Contains(t, Details(err), "stopped: context deadline exceeded")
+}
+
+func TestRetryPolicy_Defaults(t *testing.T) {
+
+	// Zero values use the defaults, except Jitter, where zero disables randomization
+	expected := NewRetryPolicy()
+	expected.Jitter = 0
+	require.Equal(t, expected, RetryPolicy{}.withDefaults())
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
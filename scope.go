@@ -0,0 +1,120 @@
+package derp
+
+import "context"
+
+// Scope is a set of reporters, with its own defaults, that replaces the global Plugins for
+// part of a program: a single test (so that parallel tests do not share reporters), or a
+// single tenant of a multi-tenant server.  Attach a Scope to a context with ContextWithScope,
+// and report errors with ReportContext, which falls back to the global Plugins when the
+// context has no Scope.
+//
+// A zero-value Scope is usable, and reports nothing until reporters are added.
+type Scope struct {
+	Plugins     ReporterList   // Reporters that receive every error reported through this Scope
+	MinSeverity Severity       // Errors below this severity are dropped (in addition to SetMinSeverity)
+	Fields      map[string]any // Default fields (such as "tenant") added to every derp.Error reported through this Scope
+	Tags        []string       // Default tags added to every derp.Error reported through this Scope
+}
+
+// NewScope returns a Scope that reports errors to the provided reporters.
+func NewScope(reporters ...Reporter) *Scope {
+
+	result := &Scope{}
+	result.Plugins.Set(reporters...)
+
+	return result
+}
+
+// Report reports an error to every reporter in this Scope, instead of the global Plugins.
+// Errors are filtered by severity and redacted, just like Report does.  Default Fields and
+// Tags are added to derp.Errors, but fields already set on the error take precedence.
+func (scope *Scope) Report(err error) {
+
+	if IsNil(err) {
+		return
+	}
+
+	if severity := ErrorSeverity(err); severity < scope.MinSeverity {
+		return
+	}
+
+	report(&scope.Plugins, scope.applyDefaults(err))
+}
+
+// applyDefaults adds the default Fields and Tags of this Scope to a derp.Error.
+// Other errors are returned unchanged.
+func (scope *Scope) applyDefaults(err error) error {
+
+	if len(scope.Fields) == 0 && len(scope.Tags) == 0 {
+		return err
+	}
+
+	var result Error
+
+	switch typed := err.(type) {
+	case Error:
+		result = typed
+	case *Error:
+		result = *typed
+	default:
+		return err
+	}
+
+	// RULE: Copy before writing, so that the caller's error is never modified.
+	existing := result.GetFields()
+	fields := copyFields(result.Fields)
+
+	for key, value := range scope.Fields {
+
+		if _, exists := existing[key]; exists {
+			continue
+		}
+
+		if fields == nil {
+			fields = make(map[string]any, len(scope.Fields))
+		}
+
+		fields[key] = value
+	}
+
+	result.Fields = fields
+
+	result.Tags = appendTags(appendTags(nil, result.Tags...), scope.Tags...)
+
+	return result
+}
+
+/******************************************
+ * Context Integration
+ ******************************************/
+
+// scopeKey is the context key that holds a Scope.
+type scopeKey struct{}
+
+// ContextWithScope returns a copy of the context that carries the provided Scope.
+func ContextWithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the Scope carried by the context, or nil if there is none.
+func ScopeFromContext(ctx context.Context) *Scope {
+
+	if ctx == nil {
+		return nil
+	}
+
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// ReportContext reports an error to the Scope carried by the context.
+// If the context has no Scope, the error is reported to the global Plugins.
+func ReportContext(ctx context.Context, err error) {
+
+	if scope := ScopeFromContext(ctx); scope != nil {
+		scope.Report(err)
+		return
+	}
+
+	Report(err)
+}
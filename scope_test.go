@@ -0,0 +1,97 @@
+package derp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope_Report(t *testing.T) {
+
+	recorder := &recordingPlugin{}
+	scope := NewScope(recorder)
+
+	err := NotFound("location", "failed for user@example.com")
+	scope.Report(err)
+
+	// Errors are redacted, just like Report does
+	require.Equal(t, "failed for [REDACTED]", Message(recorder.err))
+
+	// Nil errors are never reported
+	recorder.err = nil
+	scope.Report(nil)
+	require.Nil(t, recorder.err)
+}
+
+func TestScope_MinSeverity(t *testing.T) {
+
+	recorder := &recordingPlugin{}
+	scope := NewScope(recorder)
+	scope.MinSeverity = SeverityError
+
+	scope.Report(NotFound("location", "message"))
+	require.Nil(t, recorder.err)
+
+	scope.Report(Internal("location", "message"))
+	require.NotNil(t, recorder.err)
+}
+
+func TestScope_Defaults(t *testing.T) {
+
+	recorder := &recordingPlugin{}
+	scope := NewScope(recorder)
+	scope.Fields = map[string]any{"tenant": "acme", "userId": 0}
+	scope.Tags = []string{"tenant-acme"}
+
+	inner := NotFound("inner", "message", WithField("userId", 42))
+	err := Wrap(inner, "outer", "message", WithTags("db")).(Error)
+	scope.Report(err)
+
+	// Fields already set anywhere in the chain take precedence over defaults
+	require.Equal(t, map[string]any{"tenant": "acme", "userId": 42}, AllFields(recorder.err))
+	require.Equal(t, []string{"db", "tenant-acme"}, AllTags(recorder.err))
+
+	// The caller's error is never modified
+	require.Nil(t, err.Fields)
+	require.Equal(t, []string{"db"}, err.Tags)
+
+	// Pointers are supported too
+	scope.Report(&err)
+	require.Equal(t, "acme", AllFields(recorder.err)["tenant"])
+}
+
+func TestScope_ReportContext(t *testing.T) {
+
+	original := Plugins.Reporters()
+	t.Cleanup(func() { Plugins.Set(original...) })
+
+	global := &recordingPlugin{}
+	Plugins.Set(global)
+
+	scoped := &recordingPlugin{}
+	ctx := ContextWithScope(context.Background(), NewScope(scoped))
+
+	require.NotNil(t, ScopeFromContext(ctx))
+	require.Nil(t, ScopeFromContext(context.Background()))
+
+	// Scoped contexts report to their own reporters
+	ReportContext(ctx, Internal("scoped", "message"))
+	require.Equal(t, "scoped", Location(scoped.err))
+	require.Nil(t, global.err)
+
+	// Other contexts fall back to the global Plugins
+	ReportContext(context.Background(), Internal("global", "message"))
+	require.Equal(t, "global", Location(global.err))
+}
+
+func TestScope_ZeroValue(t *testing.T) {
+
+	var scope Scope
+	scope.Report(Internal("location", "message"))
+
+	recorder := &recordingPlugin{}
+	scope.Plugins.Add(recorder)
+	scope.Report(Internal("location", "message"))
+	require.NotNil(t, recorder.err)
+}
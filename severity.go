@@ -0,0 +1,213 @@
+package derp
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Severity describes how bad an error is, independently of its error code.
+// A "user typo" and a "disk corrupt" may share a (500) code, but not a Severity.
+type Severity int
+
+const (
+	// SeverityUnset means that no severity was chosen, so it is derived from the error code.
+	SeverityUnset Severity = iota
+
+	// SeverityDebug describes errors that are only interesting while debugging.
+	SeverityDebug
+
+	// SeverityInfo describes expected errors, such as a request for a missing record.
+	SeverityInfo
+
+	// SeverityWarning describes errors caused by the caller, such as invalid input.
+	SeverityWarning
+
+	// SeverityError describes unexpected failures that need attention.
+	SeverityError
+
+	// SeverityCritical describes failures that need attention immediately, such as data corruption.
+	SeverityCritical
+)
+
+// minSeverity is the minimum severity that Report passes on to reporters.
+var minSeverity atomic.Int64
+
+// SetMinSeverity sets the minimum severity that Report passes on to reporters.  Errors
+// below it are dropped.  SeverityUnset (the default) reports errors of every severity.
+func SetMinSeverity(minimum Severity) {
+	minSeverity.Store(int64(minimum))
+}
+
+// ParseSeverity converts a severity name (such as "warning") into a Severity.
+func ParseSeverity(value string) (Severity, error) {
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return SeverityUnset, nil
+	case "debug":
+		return SeverityDebug, nil
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+
+	return SeverityUnset, Validation("Unrecognized severity", value, WithLocation("derp.ParseSeverity"))
+}
+
+// String implements the fmt.Stringer interface.
+func (severity Severity) String() string {
+
+	switch severity {
+	case SeverityDebug:
+		return "debug"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	}
+
+	return ""
+}
+
+// MarshalText implements the encoding.TextMarshaler interface,
+// so that severities are serialized by name.
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (severity *Severity) UnmarshalText(text []byte) error {
+
+	parsed, err := ParseSeverity(string(text))
+
+	if err != nil {
+		return err
+	}
+
+	*severity = parsed
+	return nil
+}
+
+// ErrorSeverity returns the severity of any error.  Errors that implement SeverityGetter
+// (including every derp.Error) report their own severity.  Otherwise, the severity is
+// derived from the error code.
+func ErrorSeverity(err error) Severity {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return SeverityUnset
+	}
+
+	if getter, ok := AsType[SeverityGetter](err); ok {
+		return getter.GetSeverity()
+	}
+
+	return codeSeverity(ErrorCode(err))
+}
+
+// explicitSeverity returns the highest severity that was chosen explicitly anywhere
+// in the chain, or SeverityUnset if every severity would be derived from a code.
+func explicitSeverity(err error) Severity {
+
+	result := SeverityUnset
+
+	for NotNil(err) {
+
+		var severity Severity
+
+		switch typed := err.(type) {
+		case Error:
+			severity = typed.Severity
+		case *Error:
+			severity = typed.Severity
+		case SeverityGetter:
+			severity = typed.GetSeverity()
+		}
+
+		if severity > result {
+			result = severity
+		}
+
+		unwrapper, ok := err.(Unwrapper)
+
+		if !ok {
+			break
+		}
+
+		err = unwrapper.Unwrap()
+	}
+
+	return result
+}
+
+// codeSeverity derives a default severity from an error code.  Missing resources are
+// expected, other client errors are the caller's fault, and everything else (including
+// server errors and non-HTTP codes) is a real error.
+func codeSeverity(code int) Severity {
+
+	// Registered application codes are classified by the HTTP status that they map onto
+	code = mappedStatus(code)
+
+	switch {
+
+	case code == codeNotFoundError, code == codeGoneError:
+		return SeverityInfo
+
+	case code >= 100 && code < 400:
+		return SeverityInfo
+
+	case code >= 400 && code < 500:
+		return SeverityWarning
+	}
+
+	return SeverityError
+}
+
+// FilterBySeverity returns a Reporter that only passes errors of at least
+// the minimum severity on to the provided reporter.
+func FilterBySeverity(minimum Severity, reporter Reporter) Reporter {
+	return severityFilter{minimum: minimum, reporter: reporter}
+}
+
+// severityFilter is a Reporter that drops errors below a minimum severity.
+type severityFilter struct {
+	minimum  Severity
+	reporter Reporter
+}
+
+// Report implements the Reporter interface.
+func (filter severityFilter) Report(err error) {
+
+	if filter.matches(err) {
+		filter.reporter.Report(err)
+	}
+}
+
+// deliver passes a ReporterList delivery on to the filtered reporter, if the error matches.
+func (filter severityFilter) deliver(err error) error {
+
+	if filter.matches(err) {
+		return deliverTo(filter.reporter, err)
+	}
+
+	return nil
+}
+
+// innerReporter returns the filtered reporter.
+func (filter severityFilter) innerReporter() Reporter {
+	return filter.reporter
+}
+
+// matches returns TRUE if the error is at least the minimum severity.
+func (filter severityFilter) matches(err error) bool {
+	return ErrorSeverity(err) >= filter.minimum
+}
@@ -0,0 +1,115 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeverity_Defaults(t *testing.T) {
+
+	require.Equal(t, SeverityUnset, ErrorSeverity(nil))
+	require.Equal(t, SeverityInfo, ErrorSeverity(NotFound("", "")))
+	require.Equal(t, SeverityInfo, ErrorSeverity(Gone("", "")))
+	require.Equal(t, SeverityWarning, ErrorSeverity(BadRequest("", "")))
+	require.Equal(t, SeverityWarning, ErrorSeverity(Validation("")))
+	require.Equal(t, SeverityError, ErrorSeverity(Internal("", "")))
+	require.Equal(t, SeverityError, ErrorSeverity(Timeout("", "")))
+	require.Equal(t, SeverityError, ErrorSeverity(errors.New("generic")))
+	require.Equal(t, SeverityError, ErrorSeverity(newError(1001, "", "")))
+	require.Equal(t, SeverityWarning, ErrorSeverity(HTTPError{Response: HTTPResponseReport{StatusCode: 400}}))
+}
+
+func TestSeverity_Explicit(t *testing.T) {
+
+	err := Internal("location", "disk corrupt", WithSeverity(SeverityCritical))
+	require.Equal(t, SeverityCritical, ErrorSeverity(err))
+
+	// Explicit severities survive wrapping, and through standard wrappers too
+	wrapped := Wrap(err, "outer", "message")
+	require.Equal(t, SeverityCritical, ErrorSeverity(wrapped))
+	require.Equal(t, SeverityCritical, ErrorSeverity(fmt.Errorf("wrapped: %w", wrapped)))
+
+	// ...unless the outer error chooses its own
+	require.Equal(t, SeverityWarning, ErrorSeverity(Wrap(err, "outer", "message", WithSeverity(SeverityWarning))))
+
+	// The highest explicit severity in the chain wins
+	inner := Internal("inner", "message", WithSeverity(SeverityDebug))
+	middle := Wrap(inner, "middle", "message", WithSeverity(SeverityError))
+	require.Equal(t, SeverityError, ErrorSeverity(Wrap(middle, "outer", "message")))
+
+	// Without any explicit severity, the outer code decides
+	require.Equal(t, SeverityWarning, ErrorSeverity(Wrap(Internal("inner", "message"), "outer", "message", WithBadRequest())))
+}
+
+func TestSeverity_JSON(t *testing.T) {
+
+	// Derived severities are serialized by name
+	require.Contains(t, Serialize(NotFound("location", "message")), `"severity":"info"`)
+	require.Contains(t, Serialize(Internal("location", "message", WithSeverity(SeverityCritical))), `"severity":"critical"`)
+
+	// ...and parsed back
+	var parsed Error
+	require.NoError(t, json.Unmarshal([]byte(Serialize(Internal("location", "message", WithSeverity(SeverityDebug)))), &parsed))
+	require.Equal(t, SeverityDebug, parsed.Severity)
+
+	require.Error(t, json.Unmarshal([]byte(`{"severity":"catastrophic"}`), &parsed))
+}
+
+func TestParseSeverity(t *testing.T) {
+
+	for _, severity := range []Severity{SeverityDebug, SeverityInfo, SeverityWarning, SeverityError, SeverityCritical} {
+		parsed, err := ParseSeverity(severity.String())
+		require.NoError(t, err)
+		require.Equal(t, severity, parsed)
+	}
+
+	parsed, err := ParseSeverity(" WARN ")
+	require.NoError(t, err)
+	require.Equal(t, SeverityWarning, parsed)
+
+	parsed, err = ParseSeverity("")
+	require.NoError(t, err)
+	require.Equal(t, SeverityUnset, parsed)
+
+	_, err = ParseSeverity("catastrophic")
+	require.True(t, IsValidationError(err))
+	require.Equal(t, "derp.ParseSeverity", Location(err))
+}
+
+func TestSeverity_Report(t *testing.T) {
+
+	original := Plugins.slice()
+	t.Cleanup(func() {
+		Plugins.Set(original...)
+		SetMinSeverity(SeverityUnset)
+	})
+
+	counter := &countingPlugin{}
+	Plugins.Set(counter)
+	SetMinSeverity(SeverityError)
+
+	Report(NotFound("location", "message"))
+	Report(BadRequest("location", "message"))
+	require.Equal(t, 0, counter.count)
+
+	Report(Internal("location", "message"))
+	Report(NotFound("location", "message", WithSeverity(SeverityCritical)))
+	require.Equal(t, 2, counter.count)
+}
+
+func TestFilterBySeverity(t *testing.T) {
+
+	counter := &countingPlugin{}
+	filter := FilterBySeverity(SeverityWarning, counter)
+
+	filter.Report(NotFound("location", "message"))
+	require.Equal(t, 0, counter.count)
+
+	filter.Report(BadRequest("location", "message"))
+	filter.Report(Internal("location", "message"))
+	require.Equal(t, 2, counter.count)
+}
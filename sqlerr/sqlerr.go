@@ -0,0 +1,162 @@
+// Package sqlerr classifies errors from database/sql and its drivers as derp errors, so that
+// a data layer does not have to translate sql.ErrNoRows and constraint violations by hand.
+//
+// Drivers report constraint violations with SQLSTATE codes.  Any driver error that exposes
+// a `SQLState() string` method (such as those from lib/pq and pgx) is recognized.
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+package sqlerr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/benpate/derp"
+)
+
+// SQLSTATE codes that are classified individually.
+const (
+	stateUniqueViolation      = "23505"
+	stateForeignKeyViolation  = "23503"
+	stateNotNullViolation     = "23502"
+	stateCheckViolation       = "23514"
+	stateSerializationFailure = "40001"
+	stateDeadlockDetected     = "40P01"
+	stateQueryCanceled        = "57014"
+	stateInsufficientAccess   = "42501"
+)
+
+// codeTimeout is derp's unofficial (524) Timeout code.
+const codeTimeout = 524
+
+// sqlStater is implemented by driver errors that expose a SQLSTATE code.
+type sqlStater interface {
+	SQLState() string
+}
+
+// Wrap wraps an error returned by database/sql in a derp error with the matching code.
+// sql.ErrNoRows becomes a (404) Not Found, unique and foreign key violations become
+// (409) Conflicts, and serialization failures and deadlocks are (409) Conflicts that are
+// marked retryable.  Like derp.WrapIF, it returns nil when the error is nil.
+func Wrap(err error, location string, message string, details ...any) error {
+
+	if derp.IsNil(err) {
+		return nil
+	}
+
+	options := []any{derp.WithCode(Code(err))}
+
+	if IsRetryable(err) {
+		options = append(options, derp.WithRetryable(true))
+	}
+
+	if state := SQLState(err); state != "" {
+		options = append(options, "SQLSTATE "+state)
+	}
+
+	return derp.Wrap(err, location, message, append(details[:len(details):len(details)], options...)...)
+}
+
+// Code returns the derp error code that best describes an error returned by database/sql.
+// Errors that are not recognized keep their own code (see derp.ErrorCode), so that a derp
+// error passed through Wrap is not rewritten, and codeless errors are (500) Internal errors.
+func Code(err error) int {
+
+	if derp.IsNil(err) {
+		return 0
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codeTimeout
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return derp.CodeClientClosedRequest
+	}
+
+	if errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) {
+		return http.StatusServiceUnavailable
+	}
+
+	state := SQLState(err)
+
+	switch state {
+
+	case stateUniqueViolation, stateForeignKeyViolation, stateSerializationFailure, stateDeadlockDetected:
+		return http.StatusConflict
+
+	case stateNotNullViolation, stateCheckViolation:
+		return http.StatusUnprocessableEntity
+
+	case stateQueryCanceled:
+		return codeTimeout
+
+	case stateInsufficientAccess:
+		return http.StatusForbidden
+	}
+
+	// Other errors are classified by their two-character SQLSTATE class
+	switch {
+
+	case strings.HasPrefix(state, "22"): // data exception
+		return http.StatusUnprocessableEntity
+
+	case strings.HasPrefix(state, "08"), strings.HasPrefix(state, "53"): // connection exception, insufficient resources
+		return http.StatusServiceUnavailable
+	}
+
+	return derp.ErrorCode(err)
+}
+
+// SQLState returns the SQLSTATE code reported by the driver, or an empty string if
+// no error in the chain exposes one.
+func SQLState(err error) string {
+
+	var stater sqlStater
+
+	if errors.As(err, &stater) {
+		return stater.SQLState()
+	}
+
+	return ""
+}
+
+// IsUniqueViolation returns TRUE if the error is a unique constraint violation (SQLSTATE 23505).
+func IsUniqueViolation(err error) bool {
+	return SQLState(err) == stateUniqueViolation
+}
+
+// IsForeignKeyViolation returns TRUE if the error is a foreign key violation (SQLSTATE 23503).
+func IsForeignKeyViolation(err error) bool {
+	return SQLState(err) == stateForeignKeyViolation
+}
+
+// IsSerializationFailure returns TRUE if a transaction could not be serialized with
+// concurrent transactions (SQLSTATE 40001), or was chosen as a deadlock victim (40P01).
+func IsSerializationFailure(err error) bool {
+
+	switch SQLState(err) {
+	case stateSerializationFailure, stateDeadlockDetected:
+		return true
+	}
+
+	return false
+}
+
+// IsRetryable returns TRUE if the failed statement or transaction is worth retrying:
+// serialization failures, deadlocks, and lost connections.
+func IsRetryable(err error) bool {
+
+	if IsSerializationFailure(err) {
+		return true
+	}
+
+	return Code(err) == http.StatusServiceUnavailable
+}
@@ -0,0 +1,182 @@
+package sqlerr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+)
+
+// stateError is a driver error that exposes a SQLSTATE code, like those from lib/pq and pgx.
+type stateError struct {
+	state string
+}
+
+func (err stateError) Error() string    { return "driver error " + err.state }
+func (err stateError) SQLState() string { return err.state }
+
+// fakeDriver is a database/sql driver whose statements fail with the SQLSTATE
+// code given as the query text, or return no rows for "SELECT".
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (stmt fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, stateError{state: stmt.query}
+}
+
+func (stmt fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+
+	if stmt.query == "SELECT" {
+		return fakeRows{}, nil
+	}
+
+	return nil, stateError{state: stmt.query}
+}
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"id"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("sqlerr-fake", fakeDriver{})
+}
+
+// openFake opens a database that uses the fake driver.
+func openFake(t *testing.T) *sql.DB {
+
+	database, err := sql.Open("sqlerr-fake", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+
+	return database
+}
+
+func TestWrap_NoRows(t *testing.T) {
+
+	var id int
+	err := openFake(t).QueryRow("SELECT").Scan(&id)
+
+	wrapped := Wrap(err, "users.Load", "Error loading user", 42)
+	require.True(t, derp.IsNotFound(wrapped))
+	require.Equal(t, "users.Load", derp.Location(wrapped))
+	require.Contains(t, derp.Details(wrapped), 42)
+	require.True(t, errors.Is(wrapped, sql.ErrNoRows))
+	require.False(t, derp.IsRetryable(wrapped))
+}
+
+func TestWrap_UniqueViolation(t *testing.T) {
+
+	_, err := openFake(t).Exec(stateUniqueViolation)
+	require.True(t, IsUniqueViolation(err))
+
+	wrapped := Wrap(err, "users.Insert", "Error inserting user")
+	require.True(t, derp.IsConflict(wrapped))
+	require.Contains(t, derp.Details(wrapped), "SQLSTATE 23505")
+	require.False(t, derp.IsRetryable(wrapped))
+}
+
+func TestWrap_ForeignKeyViolation(t *testing.T) {
+
+	_, err := openFake(t).Exec(stateForeignKeyViolation)
+	require.True(t, IsForeignKeyViolation(err))
+	require.True(t, derp.IsConflict(Wrap(err, "orders.Insert", "Error inserting order")))
+}
+
+func TestWrap_SerializationFailure(t *testing.T) {
+
+	_, err := openFake(t).Exec(stateSerializationFailure)
+	require.True(t, IsSerializationFailure(err))
+
+	// Serialization failures are conflicts, but worth retrying
+	wrapped := Wrap(err, "accounts.Transfer", "Error committing transfer")
+	require.True(t, derp.IsConflict(wrapped))
+	require.True(t, derp.IsRetryable(wrapped))
+}
+
+func TestWrap_Nil(t *testing.T) {
+	require.Nil(t, Wrap(nil, "location", "message"))
+}
+
+func TestWrap_DoesNotModifyDetails(t *testing.T) {
+
+	details := make([]any, 1, 10)
+	details[0] = "first"
+
+	_ = Wrap(stateError{state: stateUniqueViolation}, "location", "message", details...)
+	require.Equal(t, []any{"first"}, details[:1])
+	require.Nil(t, details[:2][1])
+}
+
+func TestCode(t *testing.T) {
+
+	require.Equal(t, 0, Code(nil))
+	require.Equal(t, 404, Code(sql.ErrNoRows))
+	require.Equal(t, 404, Code(fmt.Errorf("wrapped: %w", sql.ErrNoRows)))
+	require.Equal(t, 524, Code(context.DeadlineExceeded))
+	require.Equal(t, 499, Code(context.Canceled))
+	require.Equal(t, 499, Code(fmt.Errorf("wrapped: %w", context.Canceled)))
+	require.Equal(t, 503, Code(sql.ErrConnDone))
+	require.Equal(t, 503, Code(driver.ErrBadConn))
+	require.Equal(t, 409, Code(stateError{state: "23505"}))
+	require.Equal(t, 409, Code(stateError{state: "23503"}))
+	require.Equal(t, 422, Code(stateError{state: "23502"}))
+	require.Equal(t, 422, Code(stateError{state: "23514"}))
+	require.Equal(t, 422, Code(stateError{state: "22001"}))
+	require.Equal(t, 409, Code(stateError{state: "40001"}))
+	require.Equal(t, 409, Code(stateError{state: "40P01"}))
+	require.Equal(t, 524, Code(stateError{state: "57014"}))
+	require.Equal(t, 403, Code(stateError{state: "42501"}))
+	require.Equal(t, 503, Code(stateError{state: "08006"}))
+	require.Equal(t, 503, Code(stateError{state: "53300"}))
+	require.Equal(t, 500, Code(stateError{state: "42P01"}))
+	require.Equal(t, 500, Code(errors.New("generic")))
+	require.Equal(t, 404, Code(derp.NotFound("location", "message")))
+	require.Equal(t, 403, Code(fmt.Errorf("wrapped: %w", derp.Forbidden("location", "message"))))
+}
+
+func TestWrap_KeepsDerpCode(t *testing.T) {
+
+	err := Wrap(derp.NotFound("location", "not found"), "outer", "message")
+	require.Equal(t, 404, derp.ErrorCode(err))
+	require.True(t, derp.IsNotFound(err))
+}
+
+func TestIsRetryable(t *testing.T) {
+
+	require.True(t, IsRetryable(stateError{state: "40001"}))
+	require.True(t, IsRetryable(stateError{state: "40P01"}))
+	require.True(t, IsRetryable(stateError{state: "08006"}))
+	require.True(t, IsRetryable(driver.ErrBadConn))
+
+	require.False(t, IsRetryable(stateError{state: "23505"}))
+	require.False(t, IsRetryable(sql.ErrNoRows))
+	require.False(t, IsRetryable(nil))
+}
+
+func TestSQLState(t *testing.T) {
+	require.Equal(t, "", SQLState(nil))
+	require.Equal(t, "", SQLState(errors.New("generic")))
+	require.Equal(t, "23505", SQLState(fmt.Errorf("wrapped: %w", stateError{state: "23505"})))
+}
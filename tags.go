@@ -0,0 +1,105 @@
+package derp
+
+// HasTag returns TRUE if the tag was applied (via WithTags) to the error,
+// or to any error that it wraps.
+func HasTag(err error, tag string) bool {
+
+	for _, value := range AllTags(err) {
+		if value == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllTags retrieves the tags of any error, merged across the whole chain of wrapped
+// errors.  Outer tags come first, and each tag appears only once.  The result is a
+// new slice that the caller may modify, or nil if the error has no tags.
+func AllTags(err error) []string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return nil
+	}
+
+	if getter, ok := AsType[TagsGetter](err); ok {
+		return appendTags(nil, getter.GetTags()...)
+	}
+
+	return nil
+}
+
+// appendTags appends each tag that is not already in the slice, skipping empty tags.
+func appendTags(slice []string, tags ...string) []string {
+
+	for _, tag := range tags {
+
+		if tag == "" || containsTag(slice, tag) {
+			continue
+		}
+
+		slice = append(slice, tag)
+	}
+
+	return slice
+}
+
+// containsTag returns TRUE if the slice includes the tag.
+func containsTag(slice []string, tag string) bool {
+
+	for _, value := range slice {
+		if value == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterByTag returns a Reporter that only passes errors carrying
+// at least one of the provided tags on to the provided reporter.
+func FilterByTag(reporter Reporter, tags ...string) Reporter {
+	return tagFilter{tags: tags, reporter: reporter}
+}
+
+// tagFilter is a Reporter that drops errors without any of its tags.
+type tagFilter struct {
+	tags     []string
+	reporter Reporter
+}
+
+// Report implements the Reporter interface.
+func (filter tagFilter) Report(err error) {
+
+	if filter.matches(err) {
+		filter.reporter.Report(err)
+	}
+}
+
+// deliver passes a ReporterList delivery on to the filtered reporter, if the error matches.
+func (filter tagFilter) deliver(err error) error {
+
+	if filter.matches(err) {
+		return deliverTo(filter.reporter, err)
+	}
+
+	return nil
+}
+
+// innerReporter returns the filtered reporter.
+func (filter tagFilter) innerReporter() Reporter {
+	return filter.reporter
+}
+
+// matches returns TRUE if the error has any of the filter's tags.
+func (filter tagFilter) matches(err error) bool {
+
+	for _, tag := range filter.tags {
+		if HasTag(err, tag) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags_WithTags(t *testing.T) {
+
+	err := Internal("location", "message", WithTags("billing", "db"), WithTags("db", "", "user-facing"))
+	require.Equal(t, []string{"billing", "db", "user-facing"}, err.Tags)
+	require.False(t, err.IsZero())
+}
+
+func TestTags_HasTag(t *testing.T) {
+
+	inner := Internal("inner", "message", WithTags("db"))
+	outer := Wrap(inner, "outer", "message", WithTags("billing"))
+
+	require.True(t, HasTag(outer, "billing"))
+	require.True(t, HasTag(outer, "db"))
+	require.False(t, HasTag(outer, "user-facing"))
+
+	// Standard wrappers are looked through
+	require.True(t, HasTag(fmt.Errorf("wrapped: %w", outer), "db"))
+
+	require.False(t, HasTag(nil, "db"))
+	require.False(t, HasTag(errors.New("plain"), "db"))
+}
+
+func TestTags_DeduplicateOnWrap(t *testing.T) {
+
+	inner := Internal("inner", "message", WithTags("db", "billing"))
+	outer := Wrap(inner, "outer", "message", WithTags("billing", "user-facing"))
+
+	require.Equal(t, []string{"billing", "user-facing", "db"}, AllTags(outer))
+	require.Nil(t, AllTags(errors.New("plain")))
+}
+
+func TestTags_JSON(t *testing.T) {
+
+	inner := Internal("inner", "message", WithTags("db"))
+	outer := Wrap(inner, "outer", "message", WithTags("billing", "db"))
+
+	var result struct {
+		Tags []string `json:"tags"`
+	}
+
+	require.Nil(t, json.Unmarshal([]byte(Serialize(outer)), &result))
+	require.Equal(t, []string{"billing", "db"}, result.Tags)
+
+	// Errors without tags omit the array entirely
+	require.NotContains(t, Serialize(Internal("location", "message")), "tags")
+}
+
+func TestTags_FilterByTag(t *testing.T) {
+
+	recorder := &recordingPlugin{}
+	reporter := FilterByTag(recorder, "billing", "db")
+
+	reporter.Report(Internal("location", "untagged"))
+	require.Nil(t, recorder.err)
+
+	reporter.Report(Internal("location", "other", WithTags("user-facing")))
+	require.Nil(t, recorder.err)
+
+	tagged := Wrap(Internal("inner", "message", WithTags("db")), "outer", "message")
+	reporter.Report(tagged)
+	require.Equal(t, tagged, recorder.err)
+}
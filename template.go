@@ -0,0 +1,117 @@
+package derp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Param returns an option that fills the named placeholder in the error message.  The
+// message passed to the constructor becomes the Template, and Message holds the rendered
+// text, so that `NotFound(location, "user {id} not found", Param("id", 123))` reads
+// "user 123 not found" but is still grouped with every other missing user.
+func Param(name string, value any) Option {
+	return func(e *Error) {
+
+		if e.Template == "" {
+			e.Template = e.Message
+		}
+
+		if e.Params == nil {
+			e.Params = make(map[string]any, 1)
+		}
+
+		e.Params[name] = value
+		e.Message = renderTemplate(e.Template, e.Params)
+	}
+}
+
+// renderTemplate replaces each {name} placeholder in the template with its parameter.
+// Placeholders without a matching parameter are left unchanged, so that a missing
+// parameter is visible in the rendered message.
+func renderTemplate(template string, params map[string]any) string {
+
+	var result strings.Builder
+	result.Grow(len(template))
+
+	for {
+		start := strings.IndexByte(template, '{')
+
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+
+		if end < 0 {
+			break
+		}
+
+		end += start
+		name := template[start+1 : end]
+
+		if value, ok := params[name]; ok {
+			result.WriteString(template[:start])
+			result.WriteString(fmt.Sprint(value))
+		} else {
+			result.WriteString(template[:end+1])
+		}
+
+		template = template[end+1:]
+	}
+
+	result.WriteString(template)
+	return result.String()
+}
+
+// Template retrieves the message template of any error.  Errors that were not created
+// from a template (including non-derp errors) use their message as their template.
+func Template(err error) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	if getter, ok := err.(TemplateGetter); ok {
+		return getter.GetTemplate()
+	}
+
+	return Message(err)
+}
+
+// Fingerprint returns a short, stable identifier that groups similar errors together.
+// It is derived from the error code and from the location and template of every error
+// in the chain, so it ignores parameters, details, and timestamps.
+func Fingerprint(err error) string {
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return ""
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(strconv.Itoa(ErrorCode(err))))
+
+	for NotNil(err) {
+
+		// Only templated messages are stable enough to identify an error.  Other
+		// errors are identified by their type, which ignores variable text.
+		if getter, ok := err.(TemplateGetter); ok {
+			_, _ = hash.Write([]byte("\x00" + Location(err) + "\x00" + getter.GetTemplate()))
+		} else {
+			_, _ = fmt.Fprintf(hash, "\x00%T", err)
+		}
+
+		unwrapper, ok := err.(Unwrapper)
+
+		if !ok {
+			break
+		}
+
+		err = unwrapper.Unwrap()
+	}
+
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
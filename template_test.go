@@ -0,0 +1,83 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate_Param(t *testing.T) {
+
+	err := NotFound("location", "user {id} not found in {table}", Param("id", 123), Param("table", "users"), "detail")
+
+	require.Equal(t, "user 123 not found in users", err.Message)
+	require.Equal(t, "user {id} not found in {table}", err.Template)
+	require.Equal(t, map[string]any{"id": 123, "table": "users"}, err.Params)
+	require.Equal(t, []any{"detail"}, err.Details)
+	require.False(t, err.IsZero())
+}
+
+func TestTemplate_Render(t *testing.T) {
+
+	params := map[string]any{"id": 123, "name": "Alice"}
+
+	require.Equal(t, "no placeholders", renderTemplate("no placeholders", params))
+	require.Equal(t, "123/Alice", renderTemplate("{id}/{name}", params))
+	require.Equal(t, "missing {other} stays", renderTemplate("missing {other} stays", params))
+	require.Equal(t, "unclosed {id", renderTemplate("unclosed {id", params))
+	require.Equal(t, "{}", renderTemplate("{}", params))
+}
+
+func TestTemplate_WithMessage(t *testing.T) {
+
+	// Replacing the message of a templated error re-renders it
+	err := NotFound("location", "user {id} not found", Param("id", 123), WithMessage("account {id} not found"))
+	require.Equal(t, "account 123 not found", err.Message)
+	require.Equal(t, "account {id} not found", err.Template)
+}
+
+func TestTemplate_Accessor(t *testing.T) {
+
+	require.Equal(t, "", Template(nil))
+	require.Equal(t, "plain", Template(errors.New("plain")))
+	require.Equal(t, "not templated", Template(NotFound("location", "not templated")))
+	require.Equal(t, "user {id} not found", Template(NotFound("location", "user {id} not found", Param("id", 1))))
+}
+
+func TestTemplate_Fingerprint(t *testing.T) {
+
+	first := NotFound("location", "user {id} not found", Param("id", 1))
+	second := NotFound("location", "user {id} not found", Param("id", 2), "different details")
+
+	require.NotEmpty(t, Fingerprint(first))
+	require.Equal(t, Fingerprint(first), Fingerprint(second))
+
+	// Location, template, code, and the wrap chain all change the fingerprint
+	require.NotEqual(t, Fingerprint(first), Fingerprint(NotFound("other", "user {id} not found", Param("id", 1))))
+	require.NotEqual(t, Fingerprint(first), Fingerprint(NotFound("location", "account {id} not found", Param("id", 1))))
+	require.NotEqual(t, Fingerprint(first), Fingerprint(Internal("location", "user {id} not found", Param("id", 1))))
+	require.NotEqual(t, Fingerprint(first), Fingerprint(Wrap(first, "outer", "message")))
+
+	// Non-derp errors are identified by type, not by their variable text
+	require.Equal(t,
+		Fingerprint(Wrap(fmt.Errorf("id %d", 1), "location", "message")),
+		Fingerprint(Wrap(fmt.Errorf("id %d", 2), "location", "message")),
+	)
+
+	require.Equal(t, "", Fingerprint(nil))
+}
+
+func TestTemplate_JSON(t *testing.T) {
+
+	err := NotFound("location", "user {email} not found", Param("email", "user@example.com"))
+
+	var result map[string]any
+	require.Nil(t, json.Unmarshal([]byte(Serialize(err)), &result))
+
+	require.Equal(t, "user {email} not found", result["template"])
+	require.Equal(t, "user [REDACTED] not found", result["message"])
+	require.Equal(t, "[REDACTED]", result["params"].(map[string]any)["email"])
+}
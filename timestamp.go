@@ -0,0 +1,144 @@
+package derp
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// TimeStampFormat determines how error timestamps are written to JSON.
+type TimeStampFormat int32
+
+const (
+	// TimeStampUnix writes timestamps as whole seconds since the Unix epoch.
+	// This is the default, because it matches the JSON that derp has always written.
+	TimeStampUnix TimeStampFormat = iota
+
+	// TimeStampUnixMilli writes timestamps as milliseconds since the Unix epoch.
+	TimeStampUnixMilli
+
+	// TimeStampUnixNano writes timestamps as nanoseconds since the Unix epoch.
+	TimeStampUnixNano
+
+	// TimeStampRFC3339 writes timestamps as RFC 3339 strings, with nanosecond precision.
+	TimeStampRFC3339
+)
+
+// timeStampFormat is the format that Error.MarshalJSON uses for timestamps.
+var timeStampFormat atomic.Int32
+
+// Numeric timestamps at or above these magnitudes are read as milliseconds and nanoseconds.
+// 1e11 seconds and 1e14 milliseconds are both in the year 5138, and 1e11 milliseconds and
+// 1e14 nanoseconds are both in 1973, so every timestamp in between has an unambiguous unit.
+const (
+	minTimeStampMilli = 100_000_000_000
+	minTimeStampNano  = 100_000_000_000_000
+)
+
+// SetTimeStampFormat sets how error timestamps are written to JSON.
+func SetTimeStampFormat(format TimeStampFormat) {
+	timeStampFormat.Store(int32(format))
+}
+
+// encodeTimeStamp converts a timestamp into the value that is written to JSON.
+// Zero timestamps are written as 0 in the numeric formats, just as they always were.
+func encodeTimeStamp(timestamp time.Time) any {
+
+	format := TimeStampFormat(timeStampFormat.Load())
+
+	if format == TimeStampRFC3339 {
+		return timestamp.Format(time.RFC3339Nano)
+	}
+
+	if timestamp.IsZero() {
+		return 0
+	}
+
+	switch format {
+	case TimeStampUnixMilli:
+		return timestamp.UnixMilli()
+	case TimeStampUnixNano:
+		return timestamp.UnixNano()
+	}
+
+	return timestamp.Unix()
+}
+
+// decodeTimeStamp reads a timestamp written by encodeTimeStamp in any format, regardless of
+// the configured one, so that JSON written by another process (or before a configuration
+// change) is still read correctly.  Strings are parsed as RFC 3339, and the unit of a number
+// (seconds, milliseconds, or nanoseconds) is determined by its magnitude.
+func decodeTimeStamp(data json.RawMessage) (time.Time, error) {
+
+	if len(data) == 0 || string(data) == "null" {
+		return time.Time{}, nil
+	}
+
+	if data[0] == '"' {
+
+		var value string
+
+		if err := json.Unmarshal(data, &value); err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Parse(time.RFC3339Nano, value)
+	}
+
+	var value int64
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return time.Time{}, err
+	}
+
+	if value == 0 {
+		return time.Time{}, nil
+	}
+
+	magnitude := value
+
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+
+	switch {
+	case magnitude >= minTimeStampNano:
+		return time.Unix(0, value), nil
+	case magnitude >= minTimeStampMilli:
+		return time.UnixMilli(value), nil
+	}
+
+	return time.Unix(value, 0), nil
+}
+
+// Elapsed returns the time between the creation of the innermost error in the chain
+// and the creation of the outermost one, which shows how long an error took to
+// travel up the call stack.  Errors without timestamps are skipped.
+func Elapsed(err error) time.Duration {
+
+	var first, last time.Time
+
+	for NotNil(err) {
+
+		if getter, ok := err.(TimeStampGetter); ok {
+			if timestamp := getter.GetTimeStamp(); !timestamp.IsZero() {
+
+				if first.IsZero() {
+					first = timestamp
+				}
+
+				last = timestamp
+			}
+		}
+
+		unwrapper, ok := err.(Unwrapper)
+
+		if !ok {
+			break
+		}
+
+		err = unwrapper.Unwrap()
+	}
+
+	return first.Sub(last)
+}
@@ -0,0 +1,131 @@
+package derp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setTestClock replaces the clock for the duration of a test.
+func setTestClock(t *testing.T, now func() time.Time) {
+	SetClock(now)
+	t.Cleanup(func() { SetClock(nil) })
+}
+
+// setTestTimeStampFormat replaces the timestamp format for the duration of a test.
+func setTestTimeStampFormat(t *testing.T, format TimeStampFormat) {
+	SetTimeStampFormat(format)
+	t.Cleanup(func() { SetTimeStampFormat(TimeStampUnix) })
+}
+
+func TestTimeStamp_Clock(t *testing.T) {
+
+	fixed := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	setTestClock(t, func() time.Time { return fixed })
+
+	require.Equal(t, fixed, Now())
+	require.Equal(t, fixed, NotFound("location", "message").TimeStamp)
+	require.Equal(t, fixed, Wrap(errors.New("inner"), "location", "message").(Error).TimeStamp)
+	require.Equal(t, fixed, AsError(errors.New("inner")).TimeStamp)
+
+	// IDs use the clock for their timestamp prefix
+	require.Equal(t, fmt.Sprintf("%012x", fixed.UnixMilli()), strings.ReplaceAll(NewID(), "-", "")[:12])
+
+	SetClock(nil)
+	require.WithinDuration(t, time.Now(), Now(), time.Second)
+}
+
+func TestTimeStamp_Formats(t *testing.T) {
+
+	timestamp := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	err := Error{Code: 500, TimeStamp: timestamp}
+
+	expected := map[TimeStampFormat]string{
+		TimeStampUnix:      `"timestamp":1714979289`,
+		TimeStampUnixMilli: `"timestamp":1714979289123`,
+		TimeStampUnixNano:  `"timestamp":1714979289123456789`,
+		TimeStampRFC3339:   `"timestamp":"2024-05-06T07:08:09.123456789Z"`,
+	}
+
+	for format, value := range expected {
+		setTestTimeStampFormat(t, format)
+		serialized := Serialize(err)
+		require.Contains(t, serialized, value)
+
+		// Every format round-trips with the precision that it was written in
+		var parsed Error
+		require.Nil(t, json.Unmarshal([]byte(serialized), &parsed))
+		require.Equal(t, encodeTimeStamp(timestamp), encodeTimeStamp(parsed.TimeStamp))
+	}
+}
+
+func TestTimeStamp_CrossFormat(t *testing.T) {
+
+	timestamp := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	err := Error{Code: 500, TimeStamp: timestamp}
+
+	formats := []TimeStampFormat{TimeStampUnix, TimeStampUnixMilli, TimeStampUnixNano, TimeStampRFC3339}
+
+	// JSON written in one format is read correctly while another format is configured
+	for _, written := range formats {
+
+		setTestTimeStampFormat(t, written)
+		serialized := Serialize(err)
+		expected := encodeTimeStamp(timestamp)
+
+		for _, configured := range formats {
+
+			setTestTimeStampFormat(t, configured)
+
+			var parsed Error
+			require.Nil(t, json.Unmarshal([]byte(serialized), &parsed))
+
+			setTestTimeStampFormat(t, written)
+			require.Equal(t, expected, encodeTimeStamp(parsed.TimeStamp), "written %d, read %d", written, configured)
+		}
+	}
+
+	// Timestamps before 1970 are read by magnitude too
+	for _, value := range []string{"-631152000", "-631152000000", "-631152000000000000"} {
+		parsed, decodeError := decodeTimeStamp(json.RawMessage(value))
+		require.Nil(t, decodeError)
+		require.Equal(t, time.Unix(-631152000, 0), parsed, value)
+	}
+}
+
+func TestTimeStamp_Zero(t *testing.T) {
+
+	require.Contains(t, Serialize(Error{Code: 500}), `"timestamp":0`)
+
+	var parsed Error
+	require.Nil(t, json.Unmarshal([]byte(`{"code":500,"timestamp":0}`), &parsed))
+	require.True(t, parsed.TimeStamp.IsZero())
+
+	require.Nil(t, json.Unmarshal([]byte(`{"code":500}`), &parsed))
+	require.True(t, parsed.TimeStamp.IsZero())
+
+	require.NotNil(t, json.Unmarshal([]byte(`{"code":500,"timestamp":"yesterday"}`), &parsed))
+}
+
+func TestTimeStamp_Elapsed(t *testing.T) {
+
+	started := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	now := started
+
+	setTestClock(t, func() time.Time { return now })
+
+	inner := NotFound("inner", "message")
+	now = now.Add(250 * time.Millisecond)
+	middle := fmt.Errorf("context: %w", inner)
+	outer := Wrap(Wrap(middle, "middle", "message"), "outer", "message")
+
+	require.Equal(t, 250*time.Millisecond, Elapsed(outer))
+	require.Equal(t, time.Duration(0), Elapsed(inner))
+	require.Equal(t, time.Duration(0), Elapsed(errors.New("plain")))
+	require.Equal(t, time.Duration(0), Elapsed(nil))
+}
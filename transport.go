@@ -0,0 +1,209 @@
+package derp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultTransportBodyLimit is the number of response body bytes that a Transport captures
+// by default, because an upstream's explanation of a failure is lost once the body is closed.
+const defaultTransportBodyLimit = 4096
+
+// Transport is an http.RoundTripper that converts upstream failures into HTTPErrors, so that
+// callers can classify them with the usual helpers (such as derp.IsNotFound) instead of
+// checking status codes by hand.  Failed responses are closed, and returned as errors.
+//
+// http.Client wraps every RoundTrip error in a *url.Error, which derp's accessor
+// functions (ErrorCode, RetryAfter, and the Is* helpers) look through.
+type Transport struct {
+	Base      http.RoundTripper         // Performs each request.  If nil, http.DefaultTransport is used.
+	IsFailure func(*http.Response) bool // Reports which responses are failures.  If nil, every response with a status of 400 or above is a failure.
+	Options   []HTTPOption              // Controls what is captured from failed transactions.
+}
+
+// NewTransport returns a Transport that wraps the provided RoundTripper.  By default, it captures
+// the first 4KB of failed response bodies; additional options are applied after this default.
+func NewTransport(base http.RoundTripper, options ...HTTPOption) *Transport {
+	return &Transport{
+		Base:    base,
+		Options: append([]HTTPOption{WithResponseBody(defaultTransportBodyLimit)}, options...),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.  It returns an HTTPError for
+// transport failures, and for every response that the IsFailure function rejects.
+func (transport *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+
+	base := transport.Base
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	outbound, recorder := transport.outbound(request)
+
+	started := time.Now()
+	response, err := base.RoundTrip(outbound)
+
+	// Transport failures have no response, so they are classified from the error itself.
+	if err != nil {
+		result := WrapHTTPError(transportError(err), recorder.reported(request), nil, transport.Options...)
+		result.Duration = time.Since(started)
+		result.Attempt = AttemptFromContext(request.Context())
+		return nil, result
+	}
+
+	if !transport.isFailure(response) {
+		return response, nil
+	}
+
+	result := NewHTTPError(recorder.reported(request), response, transport.Options...)
+	result.Duration = time.Since(started)
+	result.Attempt = AttemptFromContext(request.Context())
+
+	// RULE: http.Client ignores any response that is returned alongside an error,
+	// so the body must be closed here or the connection will leak.
+	if response.Body != nil {
+		_ = response.Body.Close()
+	}
+
+	return nil, result
+}
+
+// isFailure returns TRUE if the response must be converted into an HTTPError.
+func (transport *Transport) isFailure(response *http.Response) bool {
+
+	if transport.IsFailure != nil {
+		return transport.IsFailure(response)
+	}
+
+	// Redirects (including 304 Not Modified) are not failures, so http.Client can follow them.
+	return response.StatusCode >= 400
+}
+
+// outbound returns the request to send upstream.  Request bodies that cannot be read again
+// (because the request has no GetBody) are consumed by the upstream call, so when they must
+// be captured, a copy of the request is sent whose body records what is read.
+//
+// RULE: A RoundTripper must never modify the request it was given, so the original
+// request's Body is left in place.
+func (transport *Transport) outbound(request *http.Request) (*http.Request, *recordingBody) {
+
+	capture := httpCapture{}
+
+	for _, option := range transport.Options {
+		option(&capture)
+	}
+
+	if capture.requestBodyLimit <= 0 || request.GetBody != nil || request.Body == nil || request.Body == http.NoBody {
+		return request, nil
+	}
+
+	// The extra byte lets formatBody detect truncation
+	recorder := &recordingBody{ReadCloser: request.Body, limit: capture.requestBodyLimit + 1}
+
+	result := request.WithContext(request.Context())
+	result.Body = recorder
+
+	return result, recorder
+}
+
+// recordingBody is a request body that remembers the first bytes read through it.
+// It is safe for concurrent use, because http.Transport may still be writing the
+// body when the response arrives.
+type recordingBody struct {
+	io.ReadCloser
+	lock     sync.Mutex
+	limit    int
+	recorded []byte
+}
+
+// Read implements the io.Reader interface.
+func (body *recordingBody) Read(buffer []byte) (int, error) {
+
+	count, err := body.ReadCloser.Read(buffer)
+
+	body.lock.Lock()
+	defer body.lock.Unlock()
+
+	if remaining := body.limit - len(body.recorded); remaining > 0 {
+		body.recorded = append(body.recorded, buffer[:min(count, remaining)]...)
+	}
+
+	return count, err
+}
+
+// reported returns the request that an HTTPError describes.  When a body was recorded, this
+// is a copy of the request whose GetBody replays the recorded bytes; otherwise it is the
+// request itself.  The copy is never sent.
+func (body *recordingBody) reported(request *http.Request) *http.Request {
+
+	if body == nil {
+		return request
+	}
+
+	body.lock.Lock()
+	recorded := bytes.Clone(body.recorded)
+	body.lock.Unlock()
+
+	result := request.WithContext(request.Context())
+	result.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(recorded)), nil
+	}
+
+	return result
+}
+
+// transportError classifies an error that prevented any response from being received.
+// Timeouts are reported as (524) Timeout, and other failures to reach the upstream server
+// as (502) Bad Gateway.  Canceled requests were abandoned by the caller, so they are
+// reported as (499) Client Closed Request errors.
+func transportError(err error) error {
+
+	const location = "derp.Transport.RoundTrip"
+
+	if errors.Is(err, context.Canceled) {
+		return New(CodeClientClosedRequest, location, "Request canceled", WithWrappedValue(err))
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout(location, "Request timed out", WithWrappedValue(err))
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return Timeout(location, "Request timed out", WithWrappedValue(err))
+	}
+
+	return BadGateway(location, "Unable to reach upstream server", WithWrappedValue(err))
+}
+
+/******************************************
+ * Attempt Counting
+ *****************************************/
+
+// attemptKey is the context key that records the attempt number of a retried operation.
+type attemptKey struct{}
+
+// ContextWithAttempt returns a copy of the context that records which attempt of a
+// retried operation is in progress.  Transport copies it into the HTTPErrors it returns.
+func ContextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the attempt number recorded by ContextWithAttempt.
+// Operations that are not being retried are always on their first attempt.
+func AttemptFromContext(ctx context.Context) int {
+
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok && attempt > 0 {
+		return attempt
+	}
+
+	return 1
+}
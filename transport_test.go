@@ -0,0 +1,240 @@
+package derp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_Success(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+}
+
+func TestTransport_NotFound(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain")
+		writer.WriteHeader(http.StatusNotFound)
+		_, _ = writer.Write([]byte("no such widget"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	response, err := client.Get(server.URL + "/widgets/42")
+	require.Nil(t, response)
+	require.Error(t, err)
+
+	// Classification works through the *url.Error added by http.Client
+	require.True(t, IsNotFound(err))
+	require.Equal(t, 404, ErrorCode(err))
+
+	httpError := UnwrapHTTPError(err)
+	require.NotNil(t, httpError)
+	require.Equal(t, server.URL+"/widgets/42", httpError.Request.URL)
+	require.Equal(t, "no such widget", httpError.Response.Body)
+	require.Equal(t, 1, httpError.Attempt)
+	require.Positive(t, httpError.Duration)
+}
+
+func TestTransport_TooManyRequests(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Retry-After", "30")
+		writer.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	_, err := client.Get(server.URL)
+
+	isTooMany, retryAfter := IsTooManyRequests(err)
+	require.True(t, isTooMany)
+	require.Equal(t, 30*time.Second, retryAfter)
+}
+
+func TestTransport_IsFailure(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	// A custom IsFailure lets callers handle some statuses themselves
+	transport := NewTransport(nil)
+	transport.IsFailure = func(response *http.Response) bool {
+		return response.StatusCode >= 500
+	}
+
+	client := &http.Client{Transport: transport}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	require.NoError(t, response.Body.Close())
+}
+
+func TestTransport_Redirects(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+
+		switch request.URL.Path {
+
+		case "/old":
+			http.Redirect(writer, request, "/new", http.StatusFound)
+
+		case "/cached":
+			writer.WriteHeader(http.StatusNotModified)
+
+		default:
+			_, _ = writer.Write([]byte("moved"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	// Redirects are followed by http.Client, instead of being reported as errors
+	response, err := client.Get(server.URL + "/old")
+	require.NoError(t, err)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, "moved", string(body))
+
+	// Conditional GETs receive their 304 Not Modified
+	response, err = client.Get(server.URL + "/cached")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotModified, response.StatusCode)
+	require.NoError(t, response.Body.Close())
+}
+
+func TestTransport_RequestBody(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = io.ReadAll(request.Body)
+		writer.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	// A reader without GetBody, which the transport cannot read a second time
+	body := io.NopCloser(io.MultiReader(strings.NewReader(`{"name":`), strings.NewReader(`"widget"}`)))
+
+	request, err := http.NewRequest(http.MethodPost, server.URL, body)
+	require.NoError(t, err)
+	require.Nil(t, request.GetBody)
+	request.Header.Set("Content-Type", "text/plain")
+
+	client := &http.Client{Transport: NewTransport(nil, WithRequestBody(1024))}
+
+	_, err = client.Do(request)
+	require.Error(t, err)
+
+	// The body is captured without modifying the caller's request
+	require.Equal(t, `{"name":"widget"}`, UnwrapHTTPError(err).Request.Body)
+	require.Equal(t, body, request.Body)
+}
+
+func TestTransport_Unreachable(t *testing.T) {
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	_, err := client.Get(url)
+	require.Error(t, err)
+	require.True(t, IsBadGateway(err))
+
+	httpError := UnwrapHTTPError(err)
+	require.NotNil(t, httpError)
+	require.Equal(t, url, httpError.Request.URL)
+	require.NotEmpty(t, httpError.Error())
+}
+
+func TestTransport_Timeout(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, request *http.Request) {
+		<-request.Context().Done()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(request)
+	require.Error(t, err)
+	require.Equal(t, codeTimeout, ErrorCode(err))
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestTransport_Canceled(t *testing.T) {
+
+	transport := &Transport{Base: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, context.Canceled
+	})}
+
+	request, err := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	require.NoError(t, err)
+
+	_, err = transport.RoundTrip(request)
+	require.Equal(t, CodeClientClosedRequest, ErrorCode(err))
+	require.Equal(t, SeverityWarning, ErrorSeverity(err))
+	require.Equal(t, "client_closed_request", CodeName(err))
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestTransport_Attempt(t *testing.T) {
+
+	transport := &Transport{Base: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 503, Status: "503 Service Unavailable", Request: request}, nil
+	})}
+
+	request, err := http.NewRequestWithContext(ContextWithAttempt(context.Background(), 3), http.MethodGet, "https://example.com", nil)
+	require.NoError(t, err)
+
+	_, err = transport.RoundTrip(request)
+	require.Equal(t, 3, UnwrapHTTPError(err).Attempt)
+	require.Equal(t, 503, ErrorCode(err))
+}
+
+func TestAttemptFromContext(t *testing.T) {
+	require.Equal(t, 1, AttemptFromContext(context.Background()))
+	require.Equal(t, 1, AttemptFromContext(ContextWithAttempt(context.Background(), 0)))
+	require.Equal(t, 2, AttemptFromContext(ContextWithAttempt(context.Background(), 2)))
+}
+
+// roundTripFunc adapts a function into an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return fn(request)
+}
@@ -1,5 +1,23 @@
 package derp
 
+import "errors"
+
+// AsType returns the first error in the chain (starting with the error itself, then looking
+// through standard wrappers such as fmt.Errorf with %w) that implements T.  T is usually one
+// of derp's getter interfaces, such as ErrorCodeGetter.
+func AsType[T any](err error) (T, bool) {
+
+	var result T
+
+	// double nil check to make nilaway happy
+	if IsNil(err) || err == nil {
+		return result, false
+	}
+
+	ok := errors.As(err, &result)
+	return result, ok
+}
+
 // RootCause digs into the error stack and returns the original error
 // that caused the DERP.  This is an alias for the Unwrap() function.
 func RootCause(err error) error {
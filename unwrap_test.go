@@ -2,6 +2,7 @@ package derp
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -84,3 +85,20 @@ func TestUnwrapHTTPError(t *testing.T) {
 		require.Nil(t, UnwrapHTTPError(newError(404, "location", "message")))
 	}
 }
+
+func TestAsType(t *testing.T) {
+
+	// The error itself is checked first, then standard wrappers are looked through
+	inner := NotFound("location", "message")
+	wrapped := fmt.Errorf("while loading: %w", inner)
+
+	getter, ok := AsType[ErrorCodeGetter](wrapped)
+	require.True(t, ok)
+	require.Equal(t, codeNotFoundError, getter.GetErrorCode())
+
+	_, ok = AsType[ErrorCodeGetter](errors.New("plain"))
+	require.False(t, ok)
+
+	_, ok = AsType[ErrorCodeGetter](nil)
+	require.False(t, ok)
+}
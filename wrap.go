@@ -1,7 +1,5 @@
 package derp
 
-import "time"
-
 // Wrap encapsulates an existing derp.Error, and is guaranteed to return a "Not Nil" value.
 // This function ALWAYS returns a non-nil error value.
 func Wrap(inner error, location string, message string, details ...any) error {
@@ -27,8 +25,9 @@ func Wrap(inner error, location string, message string, details ...any) error {
 		Location:     location,
 		Message:      message,
 		Details:      make([]any, 0, len(details)),
-		TimeStamp:    time.Now().Unix(),
+		TimeStamp:    Now(),
 		Code:         ErrorCode(inner),
+		ID:           newErrorID(inner),
 	}
 
 	for _, detail := range details {
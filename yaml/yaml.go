@@ -0,0 +1,39 @@
+// Package yaml lets derp read YAML configuration files.  It lives in its own module so
+// that the core derp package does not depend on a YAML parser.  Import it for its side
+// effect, which registers the ".yaml" and ".yml" extensions with derp.RegisterConfigFormat:
+//
+//	import _ "github.com/benpate/derp/yaml"
+package yaml
+
+import (
+	"encoding/json"
+
+	"github.com/benpate/derp"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+func init() {
+	derp.RegisterConfigFormat(".yaml", Decode)
+	derp.RegisterConfigFormat(".yml", Decode)
+}
+
+// Decode converts YAML content into JSON.  It is the derp.ConfigDecoder
+// that this package registers for YAML files.
+func Decode(content []byte) ([]byte, error) {
+
+	const location = "yaml.Decode"
+
+	var value any
+
+	if err := goyaml.Unmarshal(content, &value); err != nil {
+		return nil, derp.Internal(location, "Unable to parse YAML", derp.WithWrappedValue(err))
+	}
+
+	result, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, derp.Internal(location, "Unable to convert YAML to JSON", derp.WithWrappedValue(err))
+	}
+
+	return result, nil
+}
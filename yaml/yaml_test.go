@@ -0,0 +1,67 @@
+package yaml
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benpate/derp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+
+	result, err := Decode([]byte(`
+reporters:
+  - type: json
+    minSeverity: warning
+  - type: syslog
+    options:
+      network: udp
+      address: logs:514
+    tags: [billing]
+    timeout: 2s
+`))
+
+	require.Nil(t, err)
+
+	config, err := derp.ParseConfig(result)
+	require.Nil(t, err)
+
+	expected := derp.Config{
+		Reporters: []derp.ReporterConfig{
+			{Type: "json", MinSeverity: "warning"},
+			{Type: "syslog", Options: json.RawMessage(`{"address":"logs:514","network":"udp"}`), Tags: []string{"billing"}, Timeout: "2s"},
+		},
+	}
+
+	require.Equal(t, expected, config)
+}
+
+func TestDecode_Error(t *testing.T) {
+	_, err := Decode([]byte("reporters: [unclosed"))
+	require.Error(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+
+	directory := t.TempDir()
+
+	for _, name := range []string{"derp.yaml", "derp.YML"} {
+
+		filename := filepath.Join(directory, name)
+		require.Nil(t, os.WriteFile(filename, []byte("reporters:\n  - type: json\n"), 0o600))
+
+		config, err := derp.LoadConfig(filename)
+		require.Nil(t, err, name)
+		require.Equal(t, derp.Config{Reporters: []derp.ReporterConfig{{Type: "json"}}}, config, name)
+	}
+
+	// Unknown fields are still rejected
+	filename := filepath.Join(directory, "invalid.yaml")
+	require.Nil(t, os.WriteFile(filename, []byte("reporters:\n  - kind: json\n"), 0o600))
+
+	_, err := derp.LoadConfig(filename)
+	require.Error(t, err)
+}